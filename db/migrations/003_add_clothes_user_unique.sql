@@ -0,0 +1,6 @@
+DELETE FROM clothes_user a
+USING clothes_user b
+WHERE a.user_id = b.user_id AND a.clothes_id = b.clothes_id AND a.id > b.id;
+
+ALTER TABLE clothes_user
+ADD CONSTRAINT clothes_user_user_id_clothes_id_key UNIQUE (user_id, clothes_id);
@@ -0,0 +1,16 @@
+CREATE TABLE balance_ledger (
+	id BIGSERIAL PRIMARY KEY,
+	user_id INTEGER NOT NULL REFERENCES users(id),
+	amount BIGINT NOT NULL,
+	balance_after BIGINT NOT NULL,
+	reason TEXT NOT NULL,
+	source_type TEXT,
+	source_id BIGINT,
+	created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX balance_ledger_user_id_id_idx ON balance_ledger (user_id, id DESC);
+
+-- Журнал только дополняется
+CREATE RULE balance_ledger_no_update AS ON UPDATE TO balance_ledger DO INSTEAD NOTHING;
+CREATE RULE balance_ledger_no_delete AS ON DELETE TO balance_ledger DO INSTEAD NOTHING;
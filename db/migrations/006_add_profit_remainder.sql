@@ -0,0 +1,2 @@
+ALTER TABLE users
+ADD COLUMN profit_remainder BIGINT NOT NULL DEFAULT 0;
@@ -0,0 +1,22 @@
+ALTER TABLE users
+ADD COLUMN xp BIGINT NOT NULL DEFAULT 0;
+
+CREATE TABLE levels (
+	level INTEGER PRIMARY KEY,
+	xp_required BIGINT NOT NULL UNIQUE,
+	max_energy_bonus INTEGER NOT NULL DEFAULT 0,
+	tap_profit_bonus INTEGER NOT NULL DEFAULT 0,
+	reward BIGINT NOT NULL DEFAULT 0
+);
+
+INSERT INTO levels (level, xp_required, max_energy_bonus, tap_profit_bonus, reward) VALUES
+	(1, 0, 0, 0, 0),
+	(2, 1000, 100, 1, 5000),
+	(3, 5000, 100, 1, 20000),
+	(4, 15000, 150, 1, 50000),
+	(5, 40000, 150, 2, 100000),
+	(6, 100000, 200, 2, 250000),
+	(7, 250000, 200, 2, 500000),
+	(8, 500000, 250, 3, 1000000),
+	(9, 1000000, 250, 3, 2500000),
+	(10, 2500000, 300, 5, 5000000);
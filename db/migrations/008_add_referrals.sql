@@ -0,0 +1,16 @@
+ALTER TABLE users
+ADD COLUMN referral_code TEXT UNIQUE,
+ADD COLUMN referrer_id INTEGER REFERENCES users(id);
+
+UPDATE users SET referral_code = substr(md5(random()::text || id::text), 1, 8) WHERE referral_code IS NULL;
+
+CREATE TABLE referrals (
+	id SERIAL PRIMARY KEY,
+	referrer_id INTEGER NOT NULL REFERENCES users(id),
+	referee_id INTEGER NOT NULL UNIQUE REFERENCES users(id),
+	earned BIGINT NOT NULL DEFAULT 0,
+	share_remainder BIGINT NOT NULL DEFAULT 0,
+	created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX referrals_referrer_id_idx ON referrals (referrer_id);
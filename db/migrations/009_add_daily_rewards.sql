@@ -0,0 +1,9 @@
+CREATE TABLE daily_reward_claims (
+	id SERIAL PRIMARY KEY,
+	user_id INTEGER NOT NULL REFERENCES users(id),
+	day DATE NOT NULL,
+	streak INTEGER NOT NULL,
+	reward BIGINT NOT NULL,
+	created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+	UNIQUE (user_id, day)
+);
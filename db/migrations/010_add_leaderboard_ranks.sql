@@ -0,0 +1,20 @@
+CREATE MATERIALIZED VIEW leaderboard_ranks AS
+SELECT
+	id AS user_id,
+	username,
+	referrer_id,
+	balance,
+	profit_per_hour,
+	level,
+	xp,
+	RANK() OVER (ORDER BY balance DESC) AS balance_rank,
+	RANK() OVER (ORDER BY profit_per_hour DESC) AS profit_per_hour_rank,
+	RANK() OVER (ORDER BY level DESC, xp DESC) AS level_rank
+FROM users;
+
+-- Уникальный индекс нужен для REFRESH MATERIALIZED VIEW CONCURRENTLY
+CREATE UNIQUE INDEX leaderboard_ranks_user_id_idx ON leaderboard_ranks (user_id);
+CREATE INDEX leaderboard_ranks_balance_rank_idx ON leaderboard_ranks (balance_rank, user_id);
+CREATE INDEX leaderboard_ranks_profit_per_hour_rank_idx ON leaderboard_ranks (profit_per_hour_rank, user_id);
+CREATE INDEX leaderboard_ranks_level_rank_idx ON leaderboard_ranks (level_rank, user_id);
+CREATE INDEX leaderboard_ranks_referrer_id_idx ON leaderboard_ranks (referrer_id);
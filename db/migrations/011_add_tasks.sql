@@ -0,0 +1,20 @@
+CREATE TABLE tasks (
+	id SERIAL PRIMARY KEY,
+	title VARCHAR(255) NOT NULL,
+	description TEXT NOT NULL DEFAULT '',
+	type VARCHAR(32) NOT NULL CHECK (type IN ('taps', 'reach_level', 'buy_worker', 'buy_clothes', 'invite_friends', 'join_channel')),
+	target BIGINT NOT NULL DEFAULT 1 CHECK (target > 0),
+	worker_type VARCHAR(32),
+	channel VARCHAR(255),
+	reward BIGINT NOT NULL CHECK (reward >= 0),
+	is_active BOOLEAN NOT NULL DEFAULT TRUE,
+	created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE user_tasks (
+	user_id INTEGER NOT NULL REFERENCES users(id),
+	task_id INTEGER NOT NULL REFERENCES tasks(id),
+	progress BIGINT NOT NULL DEFAULT 0,
+	claimed_at TIMESTAMP,
+	PRIMARY KEY (user_id, task_id)
+);
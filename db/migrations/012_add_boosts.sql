@@ -0,0 +1,17 @@
+ALTER TABLE users
+	ADD COLUMN tap_boost_multiplier INTEGER NOT NULL DEFAULT 1,
+	ADD COLUMN tap_boost_until TIMESTAMP,
+	ADD COLUMN regen_boost_multiplier INTEGER NOT NULL DEFAULT 1,
+	ADD COLUMN regen_boost_started_at TIMESTAMP,
+	ADD COLUMN regen_boost_until TIMESTAMP;
+
+CREATE TABLE boost_activations (
+	id SERIAL PRIMARY KEY,
+	user_id INTEGER NOT NULL REFERENCES users(id),
+	boost VARCHAR(32) NOT NULL,
+	day DATE NOT NULL,
+	price BIGINT NOT NULL,
+	created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX boost_activations_user_day_idx ON boost_activations (user_id, day);
@@ -0,0 +1,7 @@
+-- Уровень работника хранится явно, а не выводится из id строки workers_upgrade
+ALTER TABLE user_workers ADD COLUMN level INTEGER NOT NULL DEFAULT 0;
+
+UPDATE user_workers uw
+SET level = wu.level
+FROM workers_upgrade wu
+WHERE wu.id = uw.id_upgrade;
@@ -0,0 +1,3 @@
+-- Индексы для выборки каталога работников с уровнями пользователя одним запросом
+CREATE INDEX IF NOT EXISTS user_workers_user_worker_idx ON user_workers (id_user, id_worker);
+CREATE INDEX IF NOT EXISTS workers_upgrade_worker_level_idx ON workers_upgrade (id_worker, level);
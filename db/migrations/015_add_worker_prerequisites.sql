@@ -0,0 +1,12 @@
+-- Условия открытия работника. Работник открыт, когда выполнены все его условия
+CREATE TABLE worker_prerequisites (
+	id SERIAL PRIMARY KEY,
+	worker_id INTEGER NOT NULL REFERENCES workers(id),
+	type VARCHAR(32) NOT NULL CHECK (type IN ('worker_level', 'user_level', 'friends')),
+	-- required_worker_id — работник, которого нужно улучшить, для типа worker_level
+	required_worker_id INTEGER REFERENCES workers(id),
+	value INTEGER NOT NULL CHECK (value > 0),
+	CHECK ((type = 'worker_level') = (required_worker_id IS NOT NULL))
+);
+
+CREATE INDEX worker_prerequisites_worker_id_idx ON worker_prerequisites (worker_id);
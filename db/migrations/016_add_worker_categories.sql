@@ -0,0 +1,16 @@
+CREATE TABLE worker_categories (
+	slug VARCHAR(32) PRIMARY KEY,
+	name VARCHAR(255) NOT NULL,
+	sort_order INTEGER NOT NULL DEFAULT 0
+);
+
+INSERT INTO worker_categories (slug, name, sort_order) VALUES
+	('worker', 'Работники', 1),
+	('army', 'Армия', 2);
+
+-- Категории, которые уже встречаются в каталоге, но не описаны выше
+INSERT INTO worker_categories (slug, name)
+SELECT DISTINCT type, type FROM workers
+ON CONFLICT (slug) DO NOTHING;
+
+ALTER TABLE workers ADD CONSTRAINT workers_type_fkey FOREIGN KEY (type) REFERENCES worker_categories (slug);
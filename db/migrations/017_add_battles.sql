@@ -0,0 +1,42 @@
+ALTER TABLE workers_upgrade
+	ADD COLUMN attack INTEGER NOT NULL DEFAULT 0,
+	ADD COLUMN defense INTEGER NOT NULL DEFAULT 0;
+
+-- Начальная сила армии равна доходу уровня
+UPDATE workers_upgrade wu
+SET attack = wu.profit, defense = wu.profit
+FROM workers w
+WHERE w.id = wu.id_worker AND w.type = 'army';
+
+ALTER TABLE users
+	ADD COLUMN army_attack BIGINT NOT NULL DEFAULT 0,
+	ADD COLUMN army_defense BIGINT NOT NULL DEFAULT 0,
+	ADD COLUMN last_raid_at TIMESTAMP,
+	ADD COLUMN last_raided_at TIMESTAMP;
+
+UPDATE users u
+SET army_attack = p.attack, army_defense = p.defense
+FROM (
+	SELECT uw.id_user, SUM(wu.attack) AS attack, SUM(wu.defense) AS defense
+	FROM user_workers uw
+	JOIN workers w ON w.id = uw.id_worker AND w.type = 'army'
+	JOIN workers_upgrade wu ON wu.id_worker = uw.id_worker AND wu.level = uw.level
+	GROUP BY uw.id_user
+) p
+WHERE p.id_user = u.id;
+
+CREATE INDEX users_army_defense_idx ON users (army_defense);
+
+CREATE TABLE battles (
+	id SERIAL PRIMARY KEY,
+	attacker_id INTEGER NOT NULL REFERENCES users(id),
+	defender_id INTEGER NOT NULL REFERENCES users(id),
+	attack BIGINT NOT NULL,
+	defense BIGINT NOT NULL,
+	winner_id INTEGER NOT NULL REFERENCES users(id),
+	stolen BIGINT NOT NULL,
+	created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX battles_attacker_id_idx ON battles (attacker_id, id);
+CREATE INDEX battles_defender_id_idx ON battles (defender_id, id);
@@ -0,0 +1,17 @@
+-- Длительность улучшения до уровня, 0 — улучшение применяется сразу
+ALTER TABLE workers_upgrade ADD COLUMN duration_seconds INTEGER NOT NULL DEFAULT 0 CHECK (duration_seconds >= 0);
+
+-- Премиальная валюта для досрочного завершения улучшений
+ALTER TABLE users ADD COLUMN gems BIGINT NOT NULL DEFAULT 0 CHECK (gems >= 0);
+
+CREATE TABLE pending_upgrades (
+	id SERIAL PRIMARY KEY,
+	user_id INTEGER NOT NULL REFERENCES users(id),
+	worker_id INTEGER NOT NULL REFERENCES workers(id),
+	upgrade_id INTEGER NOT NULL REFERENCES workers_upgrade(id),
+	completes_at TIMESTAMP NOT NULL,
+	created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+	UNIQUE (user_id, worker_id)
+);
+
+CREATE INDEX pending_upgrades_user_completes_at_idx ON pending_upgrades (user_id, completes_at);
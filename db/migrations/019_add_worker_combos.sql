@@ -0,0 +1,16 @@
+-- Комбо работников: владение всеми работниками набора на нужных уровнях
+-- увеличивает пассивный доход на bonus_percent процентов.
+-- После изменения комбо доход пользователей исправляется через POST /admin/workers/reconcile-profit
+CREATE TABLE worker_combos (
+	id SERIAL PRIMARY KEY,
+	name VARCHAR(255) NOT NULL,
+	description TEXT NOT NULL DEFAULT '',
+	bonus_percent INTEGER NOT NULL CHECK (bonus_percent > 0)
+);
+
+CREATE TABLE worker_combo_items (
+	combo_id INTEGER NOT NULL REFERENCES worker_combos(id) ON DELETE CASCADE,
+	worker_id INTEGER NOT NULL REFERENCES workers(id),
+	min_level INTEGER NOT NULL DEFAULT 1 CHECK (min_level > 0),
+	PRIMARY KEY (combo_id, worker_id)
+);
@@ -0,0 +1,25 @@
+-- Комбо дня: три случайных работника, которых нужно улучшить в течение календарного дня
+CREATE TABLE daily_combos (
+	day DATE PRIMARY KEY,
+	worker_ids INTEGER[] NOT NULL CHECK (cardinality(worker_ids) = 3),
+	reward BIGINT NOT NULL CHECK (reward >= 0),
+	created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+-- Работники комбо дня, которых пользователь улучшил в этот день
+CREATE TABLE daily_combo_progress (
+	user_id INTEGER NOT NULL REFERENCES users(id),
+	day DATE NOT NULL REFERENCES daily_combos(day),
+	worker_id INTEGER NOT NULL REFERENCES workers(id),
+	created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+	PRIMARY KEY (user_id, day, worker_id)
+);
+
+CREATE TABLE daily_combo_claims (
+	id SERIAL PRIMARY KEY,
+	user_id INTEGER NOT NULL REFERENCES users(id),
+	day DATE NOT NULL REFERENCES daily_combos(day),
+	reward BIGINT NOT NULL,
+	created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+	UNIQUE (user_id, day)
+);
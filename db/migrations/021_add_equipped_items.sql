@@ -0,0 +1,26 @@
+-- Надетая одежда хранится по id вещи, а не по url_image: смена картинки больше не ломает экипировку,
+-- а вещи с одинаковой картинкой различаются
+CREATE TABLE equipped_items (
+	user_id INTEGER NOT NULL REFERENCES users(id),
+	slot VARCHAR(16) NOT NULL CHECK (slot IN ('head', 'body', 'legs', 'foot', 'hand')),
+	clothes_id INTEGER NOT NULL REFERENCES clothes(id),
+	PRIMARY KEY (user_id, slot)
+);
+
+-- Перенос из колонок с url_image. Если картинка совпадает у нескольких вещей слота,
+-- выбирается купленная пользователем, а из них — с меньшим id
+INSERT INTO equipped_items (user_id, slot, clothes_id)
+SELECT DISTINCT ON (u.id, s.slot) u.id, s.slot, c.id
+FROM users u
+CROSS JOIN LATERAL (VALUES ('head', u.head), ('body', u.body), ('legs', u.legs), ('foot', u.foot), ('hand', u.hand)) AS s(slot, url_image)
+JOIN clothes c ON c.url_image = s.url_image AND c.type = s.slot
+LEFT JOIN clothes_user cu ON cu.user_id = u.id AND cu.clothes_id = c.id
+WHERE s.url_image IS NOT NULL
+ORDER BY u.id, s.slot, cu.id IS NULL, c.id;
+
+ALTER TABLE users
+	DROP COLUMN head,
+	DROP COLUMN body,
+	DROP COLUMN legs,
+	DROP COLUMN foot,
+	DROP COLUMN hand;
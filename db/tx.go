@@ -0,0 +1,27 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+)
+
+// WithTx выполняет fn в транзакции: коммитит при успехе и откатывает при ошибке или панике
+func WithTx(ctx context.Context, db *sql.DB, fn func(tx *sql.Tx) error) (err error) {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if p := recover(); p != nil {
+			tx.Rollback()
+			panic(p)
+		}
+		if err != nil {
+			tx.Rollback()
+			return
+		}
+		err = tx.Commit()
+	}()
+
+	return fn(tx)
+}
@@ -15,14 +15,14 @@ const docTemplate = `{
     "host": "{{.Host}}",
     "basePath": "{{.BasePath}}",
     "paths": {
-        "/clothes": {
-            "get": {
+        "/admin/tasks": {
+            "post": {
                 "security": [
                     {
-                        "TelegramAuth": []
+                        "AdminAuth": []
                     }
                 ],
-                "description": "Получает список доступной одежды",
+                "description": "Создает новое задание. Требует заголовок X-Admin-Token",
                 "consumes": [
                     "application/json"
                 ],
@@ -30,16 +30,42 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "clothes"
+                    "admin"
+                ],
+                "summary": "Создать задание",
+                "parameters": [
+                    {
+                        "description": "Задание",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/tasks.CreateTaskRequest"
+                        }
+                    }
                 ],
-                "summary": "Получить список одежды",
                 "responses": {
-                    "200": {
-                        "description": "OK",
+                    "201": {
+                        "description": "Created",
                         "schema": {
-                            "type": "array",
-                            "items": {
-                                "$ref": "#/definitions/clothes.ClotheUserResponse"
+                            "$ref": "#/definitions/tasks.TaskRepo"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
                             }
                         }
                     },
@@ -55,42 +81,42 @@ const docTemplate = `{
                 }
             }
         },
-        "/clothes/buy/{id}": {
-            "post": {
+        "/admin/tasks/{id}": {
+            "delete": {
                 "security": [
                     {
-                        "TelegramAuth": []
+                        "AdminAuth": []
                     }
                 ],
-                "description": "Покупает предмет одежды",
-                "consumes": [
-                    "application/json"
-                ],
-                "produces": [
-                    "application/json"
-                ],
+                "description": "Скрывает задание из списка. Требует заголовок X-Admin-Token",
                 "tags": [
-                    "clothes"
+                    "admin"
                 ],
-                "summary": "Купить предмет одежды",
+                "summary": "Удалить задание",
                 "parameters": [
                     {
-                        "type": "string",
-                        "description": "ID предмета одежды",
+                        "type": "integer",
+                        "description": "ID задания",
                         "name": "id",
                         "in": "path",
                         "required": true
                     }
                 ],
                 "responses": {
-                    "200": {
-                        "description": "OK",
+                    "204": {
+                        "description": "No Content"
+                    },
+                    "401": {
+                        "description": "Unauthorized",
                         "schema": {
-                            "$ref": "#/definitions/clothes.ClotheUserResponse"
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
                         }
                     },
-                    "400": {
-                        "description": "Bad Request",
+                    "404": {
+                        "description": "Not Found",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -110,14 +136,14 @@ const docTemplate = `{
                 }
             }
         },
-        "/clothes/equip/{id}": {
+        "/admin/workers/reconcile-profit": {
             "post": {
                 "security": [
                     {
-                        "TelegramAuth": []
+                        "AdminAuth": []
                     }
                 ],
-                "description": "Экипирует предмет одежды",
+                "description": "Пересчитывает доход в час по купленным работникам у пользователей, где он расходится с сохраненным. Требует заголовок X-Admin-Token",
                 "consumes": [
                     "application/json"
                 ],
@@ -125,27 +151,26 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "clothes"
+                    "admin"
                 ],
-                "summary": "Экипировать предмет одежды",
+                "summary": "Исправить доход в час",
                 "parameters": [
                     {
-                        "type": "string",
-                        "description": "ID предмета одежды",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
+                        "type": "boolean",
+                        "description": "Только показать расхождения",
+                        "name": "dry_run",
+                        "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/clothes.ClotheUserResponse"
+                            "$ref": "#/definitions/workers.ReconcileProfitResponse"
                         }
                     },
-                    "400": {
-                        "description": "Bad Request",
+                    "401": {
+                        "description": "Unauthorized",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -165,14 +190,14 @@ const docTemplate = `{
                 }
             }
         },
-        "/clothes/{id}": {
+        "/battles": {
             "get": {
                 "security": [
                     {
                         "TelegramAuth": []
                     }
                 ],
-                "description": "Получает информацию о конкретном предмете одежды",
+                "description": "Возвращает бои пользователя как нападающего и как защищающегося, новые первыми",
                 "consumes": [
                     "application/json"
                 ],
@@ -180,23 +205,37 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "clothes"
+                    "battles"
                 ],
-                "summary": "Получить предмет одежды",
+                "summary": "История боев",
                 "parameters": [
                     {
-                        "type": "string",
-                        "description": "ID предмета одежды",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
+                        "type": "integer",
+                        "description": "ID боя, после которого продолжить",
+                        "name": "cursor",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Размер страницы, не больше 100",
+                        "name": "limit",
+                        "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/clothes.ClotheUserResponse"
+                            "$ref": "#/definitions/battles.BattlesResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
                         }
                     },
                     "500": {
@@ -211,14 +250,14 @@ const docTemplate = `{
                 }
             }
         },
-        "/index": {
+        "/battles/power": {
             "get": {
                 "security": [
                     {
                         "TelegramAuth": []
                     }
                 ],
-                "description": "Получает текущее значение индекса",
+                "description": "Возвращает атаку и защиту армии пользователя и время до следующего нападения",
                 "consumes": [
                     "application/json"
                 ],
@@ -226,34 +265,36 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "indexer"
+                    "battles"
                 ],
-                "summary": "Получить текущий индекс",
+                "summary": "Сила армии",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "integer"
-                            }
+                            "$ref": "#/definitions/battles.PowerResponse"
                         }
                     },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
-                            "$ref": "#/definitions/indexer.ErrorResponse"
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
                         }
                     }
                 }
-            },
+            }
+        },
+        "/battles/raid": {
             "post": {
                 "security": [
                     {
                         "TelegramAuth": []
                     }
                 ],
-                "description": "Увеличивает значение индекса на 1",
+                "description": "Нападает на игрока с похожей силой армии. Победитель забирает часть ненакопленного пассивного дохода проигравшего",
                 "consumes": [
                     "application/json"
                 ],
@@ -261,30 +302,60 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "indexer"
+                    "battles"
                 ],
-                "summary": "Увеличить индекс",
+                "summary": "Напасть на игрока",
                 "responses": {
-                    "204": {
-                        "description": "No Content"
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/battles.BattleResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/battles.CooldownResponse"
+                        }
                     },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
-                            "$ref": "#/definitions/indexer.ErrorResponse"
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
                         }
                     }
                 }
             }
         },
-        "/index/add": {
-            "post": {
+        "/boosts": {
+            "get": {
                 "security": [
                     {
                         "TelegramAuth": []
                     }
                 ],
-                "description": "Добавляет указанное число к текущему значению индекса",
+                "description": "Возвращает бусты с ценой, оставшимися бесплатными активациями и временем действия",
                 "consumes": [
                     "application/json"
                 ],
@@ -292,48 +363,39 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "indexer"
-                ],
-                "summary": "Добавить к индексу",
-                "parameters": [
-                    {
-                        "type": "integer",
-                        "description": "Число для добавления",
-                        "name": "number",
-                        "in": "query",
-                        "required": true
-                    }
+                    "boosts"
                 ],
+                "summary": "Список бустов",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/indexer.AddToIndexResponse"
-                        }
-                    },
-                    "400": {
-                        "description": "Bad Request",
-                        "schema": {
-                            "$ref": "#/definitions/indexer.ErrorResponse"
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/boosts.BoostResponse"
+                            }
                         }
                     },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
-                            "$ref": "#/definitions/indexer.ErrorResponse"
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
                         }
                     }
                 }
             }
         },
-        "/index/double": {
+        "/boosts/{name}/activate": {
             "post": {
                 "security": [
                     {
                         "TelegramAuth": []
                     }
                 ],
-                "description": "Удваивает текущее значение индекса",
+                "description": "Активирует буст energy_refill, multitap_x2, multitap_x5 или fast_regen. Пока есть бесплатные активации за день, буст бесплатный",
                 "consumes": [
                     "application/json"
                 ],
@@ -341,36 +403,72 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "indexer"
+                    "boosts"
+                ],
+                "summary": "Активировать буст",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Название буста",
+                        "name": "name",
+                        "in": "path",
+                        "required": true
+                    }
                 ],
-                "summary": "Удвоить индекс",
                 "responses": {
-                    "204": {
-                        "description": "No Content"
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/boosts.ActivateBoostResponse"
+                        }
                     },
                     "400": {
                         "description": "Bad Request",
                         "schema": {
-                            "$ref": "#/definitions/indexer.ErrorResponse"
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
                         }
                     },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
-                            "$ref": "#/definitions/indexer.ErrorResponse"
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
                         }
                     }
                 }
             }
         },
-        "/user": {
+        "/clothes": {
             "get": {
                 "security": [
                     {
                         "TelegramAuth": []
                     }
                 ],
-                "description": "Получает информацию о текущем пользователе",
+                "description": "Получает список доступной одежды",
                 "consumes": [
                     "application/json"
                 ],
@@ -378,14 +476,17 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "user"
+                    "clothes"
                 ],
-                "summary": "Получить пользователя",
+                "summary": "Получить список одежды",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/user.UserResponse"
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/clothes.ClotheUserResponse"
+                            }
                         }
                     },
                     "500": {
@@ -398,14 +499,16 @@ const docTemplate = `{
                         }
                     }
                 }
-            },
+            }
+        },
+        "/clothes/buy/{id}": {
             "post": {
                 "security": [
                     {
                         "TelegramAuth": []
                     }
                 ],
-                "description": "Создает нового пользователя",
+                "description": "Покупает предмет одежды",
                 "consumes": [
                     "application/json"
                 ],
@@ -413,36 +516,63 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "user"
+                    "clothes"
+                ],
+                "summary": "Купить предмет одежды",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID предмета одежды",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
                 ],
-                "summary": "Создать пользователя",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/user.UserRepo"
+                            "$ref": "#/definitions/clothes.ClotheUserResponse"
                         }
                     },
-                    "500": {
-                        "description": "Internal Server Error",
+                    "400": {
+                        "description": "Bad Request",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
                                 "type": "string"
                             }
                         }
-                    }
-                }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
             }
         },
-        "/user/tap": {
+        "/clothes/equip/{id}": {
             "post": {
                 "security": [
                     {
                         "TelegramAuth": []
                     }
                 ],
-                "description": "Выполняет действие тапа для пользователя",
+                "description": "Экипирует предмет одежды",
                 "consumes": [
                     "application/json"
                 ],
@@ -450,15 +580,32 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "user"
+                    "clothes"
+                ],
+                "summary": "Экипировать предмет одежды",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID предмета одежды",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
                 ],
-                "summary": "Тап пользователя",
                 "responses": {
                     "200": {
                         "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/clothes.ClotheUserResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
                         "schema": {
                             "type": "object",
-                            "additionalProperties": true
+                            "additionalProperties": {
+                                "type": "string"
+                            }
                         }
                     },
                     "500": {
@@ -473,14 +620,14 @@ const docTemplate = `{
                 }
             }
         },
-        "/workers/army": {
+        "/clothes/{id}": {
             "get": {
                 "security": [
                     {
                         "TelegramAuth": []
                     }
                 ],
-                "description": "Получает список доступных воинов",
+                "description": "Получает информацию о конкретном предмете одежды",
                 "consumes": [
                     "application/json"
                 ],
@@ -488,18 +635,61 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "workers"
+                    "clothes"
+                ],
+                "summary": "Получить предмет одежды",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID предмета одежды",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
                 ],
-                "summary": "Получить армию",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "type": "array",
-                            "items": {
-                                "$ref": "#/definitions/workers.UserWorkerResponse"
+                            "$ref": "#/definitions/clothes.ClotheUserResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
                             }
                         }
+                    }
+                }
+            }
+        },
+        "/daily": {
+            "get": {
+                "security": [
+                    {
+                        "TelegramAuth": []
+                    }
+                ],
+                "description": "Возвращает текущую серию ежедневных входов и календарь наград",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "daily"
+                ],
+                "summary": "Календарь ежедневных наград",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/daily.DailyRewardResponse"
+                        }
                     },
                     "500": {
                         "description": "Internal Server Error",
@@ -513,14 +703,14 @@ const docTemplate = `{
                 }
             }
         },
-        "/workers/buy/{id}": {
+        "/daily/claim": {
             "post": {
                 "security": [
                     {
                         "TelegramAuth": []
                     }
                 ],
-                "description": "Покупает или улучшает работника",
+                "description": "Начисляет награду за сегодняшний день серии. Повторный запрос в тот же день ничего не начисляет",
                 "consumes": [
                     "application/json"
                 ],
@@ -528,30 +718,55 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "workers"
+                    "daily"
                 ],
-                "summary": "Купить работника",
-                "parameters": [
+                "summary": "Забрать ежедневную награду",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/daily.DailyRewardResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/daily/combo": {
+            "get": {
+                "security": [
                     {
-                        "type": "integer",
-                        "description": "ID работника",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
+                        "TelegramAuth": []
                     }
                 ],
+                "description": "Возвращает комбо дня: три работника, которых нужно улучшить сегодня. Работник показывается только после того, как пользователь его улучшил",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "daily"
+                ],
+                "summary": "Комбо дня",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "type": "array",
-                            "items": {
-                                "$ref": "#/definitions/workers.UserWorkerResponse"
-                            }
+                            "$ref": "#/definitions/daily.DailyComboResponse"
                         }
                     },
-                    "400": {
-                        "description": "Bad Request",
+                    "404": {
+                        "description": "Not Found",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -571,14 +786,14 @@ const docTemplate = `{
                 }
             }
         },
-        "/workers/worker": {
-            "get": {
+        "/daily/combo/claim": {
+            "post": {
                 "security": [
                     {
                         "TelegramAuth": []
                     }
                 ],
-                "description": "Получает список доступных работников",
+                "description": "Начисляет награду, если сегодня улучшены все работники комбо дня",
                 "consumes": [
                     "application/json"
                 ],
@@ -586,16 +801,40 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "workers"
+                    "daily"
                 ],
-                "summary": "Получить список работников",
+                "summary": "Забрать награду за комбо дня",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "type": "array",
-                            "items": {
-                                "$ref": "#/definitions/workers.UserWorkerResponse"
+                            "$ref": "#/definitions/daily.DailyComboResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
                             }
                         }
                     },
@@ -610,27 +849,1604 @@ const docTemplate = `{
                     }
                 }
             }
-        }
-    },
-    "definitions": {
-        "clothes.ClotheUserResponse": {
+        },
+        "/index": {
+            "get": {
+                "security": [
+                    {
+                        "TelegramAuth": []
+                    }
+                ],
+                "description": "Получает текущее значение индекса",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "indexer"
+                ],
+                "summary": "Получить текущий индекс",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "integer"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/indexer.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "TelegramAuth": []
+                    }
+                ],
+                "description": "Увеличивает значение индекса на 1",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "indexer"
+                ],
+                "summary": "Увеличить индекс",
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/indexer.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/index/add": {
+            "post": {
+                "security": [
+                    {
+                        "TelegramAuth": []
+                    }
+                ],
+                "description": "Добавляет указанное число к текущему значению индекса",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "indexer"
+                ],
+                "summary": "Добавить к индексу",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Число для добавления",
+                        "name": "number",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/indexer.AddToIndexResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/indexer.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/indexer.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/index/double": {
+            "post": {
+                "security": [
+                    {
+                        "TelegramAuth": []
+                    }
+                ],
+                "description": "Удваивает текущее значение индекса",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "indexer"
+                ],
+                "summary": "Удвоить индекс",
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/indexer.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/indexer.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/leaderboard": {
+            "get": {
+                "security": [
+                    {
+                        "TelegramAuth": []
+                    }
+                ],
+                "description": "Возвращает топ игроков по балансу, доходу в час или уровню среди всех игроков или среди приглашенных друзей, а также место текущего пользователя. Рейтинг пересчитывается периодически",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "leaderboard"
+                ],
+                "summary": "Рейтинг игроков",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "balance, profit_per_hour или level (по умолчанию balance)",
+                        "name": "board",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "global или referrals (по умолчанию global)",
+                        "name": "scope",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Размер топа (по умолчанию и максимум 100)",
+                        "name": "limit",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/leaderboard.LeaderboardResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/tasks": {
+            "get": {
+                "security": [
+                    {
+                        "TelegramAuth": []
+                    }
+                ],
+                "description": "Возвращает активные задания с прогрессом пользователя",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tasks"
+                ],
+                "summary": "Список заданий",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/tasks.TaskResponse"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/tasks/{id}/claim": {
+            "post": {
+                "security": [
+                    {
+                        "TelegramAuth": []
+                    }
+                ],
+                "description": "Проверяет выполнение задания и начисляет награду. Для join_channel подписка проверяется через Telegram",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tasks"
+                ],
+                "summary": "Забрать награду за задание",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID задания",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/tasks.TaskResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "502": {
+                        "description": "Bad Gateway",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/user": {
+            "get": {
+                "security": [
+                    {
+                        "TelegramAuth": []
+                    }
+                ],
+                "description": "Получает информацию о текущем пользователе",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "user"
+                ],
+                "summary": "Получить пользователя",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/user.UserResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "TelegramAuth": []
+                    }
+                ],
+                "description": "Создает нового пользователя",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "user"
+                ],
+                "summary": "Создать пользователя",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/user.UserRepo"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/user/level": {
+            "get": {
+                "security": [
+                    {
+                        "TelegramAuth": []
+                    }
+                ],
+                "description": "Возвращает текущий уровень, опыт и прогресс до следующего уровня",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "user"
+                ],
+                "summary": "Уровень пользователя",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/user.LevelProgressResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/user/referrals": {
+            "get": {
+                "security": [
+                    {
+                        "TelegramAuth": []
+                    }
+                ],
+                "description": "Возвращает реферальный код, ссылку-приглашение и список приглашенных друзей с их уровнем и отчислениями пригласившему",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "user"
+                ],
+                "summary": "Приглашенные друзья",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/referral.ReferralsResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/user/tap": {
+            "post": {
+                "security": [
+                    {
+                        "TelegramAuth": []
+                    }
+                ],
+                "description": "Выполняет действие тапа для пользователя",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "user"
+                ],
+                "summary": "Тап пользователя",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/user.OutOfEnergyResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/user/tap/batch": {
+            "post": {
+                "security": [
+                    {
+                        "TelegramAuth": []
+                    }
+                ],
+                "description": "Применяет накопленные клиентом тапы одним запросом. Количество ограничивается энергией и допустимой частотой тапов",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "user"
+                ],
+                "summary": "Пачка тапов пользователя",
+                "parameters": [
+                    {
+                        "description": "Количество тапов и время начала/конца пачки в миллисекундах",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/user.TapBatchRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/user.TapBatchResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/user.OutOfEnergyResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/user/transactions": {
+            "get": {
+                "security": [
+                    {
+                        "TelegramAuth": []
+                    }
+                ],
+                "description": "Возвращает изменения баланса пользователя от новых к старым с пагинацией по курсору",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "user"
+                ],
+                "summary": "История операций",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "next_cursor из предыдущего ответа",
+                        "name": "cursor",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Размер страницы (по умолчанию 50, максимум 100)",
+                        "name": "limit",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/ledger.TransactionsResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/workers": {
+            "get": {
+                "security": [
+                    {
+                        "TelegramAuth": []
+                    }
+                ],
+                "description": "Получает работников категории с уровнями пользователя. Без category возвращает всех работников",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "workers"
+                ],
+                "summary": "Получить список работников",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Категория работников, например worker или army",
+                        "name": "category",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/workers.UserWorkerResponse"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/workers/army": {
+            "get": {
+                "security": [
+                    {
+                        "TelegramAuth": []
+                    }
+                ],
+                "description": "Устаревший маршрут, то же, что /workers?category=army",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "workers"
+                ],
+                "summary": "Получить армию",
+                "deprecated": true,
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/workers.UserWorkerResponse"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/workers/buy/{id}": {
+            "post": {
+                "security": [
+                    {
+                        "TelegramAuth": []
+                    }
+                ],
+                "description": "Покупает или улучшает работника и возвращает работников его категории. Купить неоткрытого работника нельзя.\nУлучшение с длительностью оплачивается сразу, а доход увеличивается после его завершения",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "workers"
+                ],
+                "summary": "Купить работника",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID работника",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/workers.UserWorkerResponse"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/workers/categories": {
+            "get": {
+                "security": [
+                    {
+                        "TelegramAuth": []
+                    }
+                ],
+                "description": "Получает список категорий работников",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "workers"
+                ],
+                "summary": "Категории работников",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/workers.CategoryRepo"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/workers/combos": {
+            "get": {
+                "security": [
+                    {
+                        "TelegramAuth": []
+                    }
+                ],
+                "description": "Получает комбо работников с продвижением пользователя. Собранное комбо увеличивает пассивный доход на bonus_percent процентов",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "workers"
+                ],
+                "summary": "Комбо работников",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/workers.WorkerComboResponse"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/workers/finish/{id}": {
+            "post": {
+                "security": [
+                    {
+                        "TelegramAuth": []
+                    }
+                ],
+                "description": "Завершает идущее улучшение работника за кристаллы и возвращает работников его категории",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "workers"
+                ],
+                "summary": "Завершить улучшение досрочно",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID работника",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/workers.UserWorkerResponse"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/workers/upgrade-all": {
+            "post": {
+                "security": [
+                    {
+                        "TelegramAuth": []
+                    }
+                ],
+                "description": "Покупает самые выгодные по доходу на монету улучшения, пока хватает баланса или бюджета, в одной транзакции",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "workers"
+                ],
+                "summary": "Улучшить всех доступных работников",
+                "parameters": [
+                    {
+                        "description": "Бюджет",
+                        "name": "request",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/workers.UpgradeAllRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/workers.UpgradeAllResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/workers/worker": {
+            "get": {
+                "security": [
+                    {
+                        "TelegramAuth": []
+                    }
+                ],
+                "description": "Устаревший маршрут, то же, что /workers?category=worker",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "workers"
+                ],
+                "summary": "Получить работников категории worker",
+                "deprecated": true,
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/workers.UserWorkerResponse"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        }
+    },
+    "definitions": {
+        "battles.BattleResponse": {
+            "type": "object",
+            "properties": {
+                "attack": {
+                    "type": "integer"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "defense": {
+                    "type": "integer"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "opponent_username": {
+                    "type": "string"
+                },
+                "role": {
+                    "type": "string"
+                },
+                "stolen": {
+                    "description": "Stolen — сколько монет забрал победитель",
+                    "type": "integer"
+                },
+                "won": {
+                    "type": "boolean"
+                }
+            }
+        },
+        "battles.BattlesResponse": {
+            "type": "object",
+            "properties": {
+                "items": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/battles.BattleResponse"
+                    }
+                },
+                "next_cursor": {
+                    "type": "integer"
+                }
+            }
+        },
+        "battles.CooldownResponse": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "type": "string"
+                },
+                "next_raid_in": {
+                    "type": "integer"
+                }
+            }
+        },
+        "battles.PowerResponse": {
+            "type": "object",
+            "properties": {
+                "attack": {
+                    "type": "integer"
+                },
+                "defense": {
+                    "type": "integer"
+                },
+                "next_raid_in": {
+                    "description": "NextRaidIn — через сколько секунд можно напасть снова, 0 если уже можно",
+                    "type": "integer"
+                }
+            }
+        },
+        "boosts.ActivateBoostResponse": {
+            "type": "object",
+            "properties": {
+                "balance": {
+                    "type": "integer"
+                },
+                "boost": {
+                    "$ref": "#/definitions/boosts.BoostResponse"
+                },
+                "paid": {
+                    "description": "Paid — сколько монет списано, 0 для бесплатной активации",
+                    "type": "integer"
+                }
+            }
+        },
+        "boosts.BoostResponse": {
+            "type": "object",
+            "properties": {
+                "duration_seconds": {
+                    "type": "integer"
+                },
+                "free_left": {
+                    "type": "integer"
+                },
+                "free_per_day": {
+                    "type": "integer"
+                },
+                "multiplier": {
+                    "type": "integer"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "price": {
+                    "type": "integer"
+                },
+                "remaining_seconds": {
+                    "description": "RemainingSeconds — сколько еще действует буст этого типа, 0 если не активен",
+                    "type": "integer"
+                }
+            }
+        },
+        "clothes.ClotheUserResponse": {
+            "type": "object",
+            "properties": {
+                "can_buy": {
+                    "type": "boolean"
+                },
+                "description": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "is_active": {
+                    "type": "boolean"
+                },
+                "is_bought": {
+                    "type": "boolean"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "per_for_tap": {
+                    "type": "integer"
+                },
+                "plus_energy": {
+                    "type": "integer"
+                },
+                "price": {
+                    "type": "integer"
+                },
+                "rarity": {
+                    "type": "string"
+                },
+                "type": {
+                    "type": "string"
+                },
+                "url_image": {
+                    "type": "string"
+                }
+            }
+        },
+        "daily.CalendarDay": {
+            "type": "object",
+            "properties": {
+                "claimed": {
+                    "type": "boolean"
+                },
+                "current": {
+                    "description": "Current — награда, которую можно забрать сегодня или которая забрана сегодня",
+                    "type": "boolean"
+                },
+                "day": {
+                    "type": "integer"
+                },
+                "reward": {
+                    "type": "integer"
+                }
+            }
+        },
+        "daily.ComboSlot": {
+            "type": "object",
+            "properties": {
+                "found": {
+                    "type": "boolean"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "url_image": {
+                    "type": "string"
+                },
+                "worker_id": {
+                    "type": "integer"
+                }
+            }
+        },
+        "daily.DailyComboResponse": {
+            "type": "object",
+            "properties": {
+                "can_claim": {
+                    "description": "CanClaim — все работники комбо улучшены, а награда еще не забрана",
+                    "type": "boolean"
+                },
+                "claimed": {
+                    "type": "boolean"
+                },
+                "day": {
+                    "type": "string"
+                },
+                "next_combo_in": {
+                    "description": "NextComboIn — секунд до выбора нового комбо",
+                    "type": "integer"
+                },
+                "reward": {
+                    "type": "integer"
+                },
+                "slots": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/daily.ComboSlot"
+                    }
+                }
+            }
+        },
+        "daily.DailyRewardResponse": {
+            "type": "object",
+            "properties": {
+                "calendar": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/daily.CalendarDay"
+                    }
+                },
+                "claimed": {
+                    "description": "Claimed — награда, начисленная этим запросом. 0, если она уже была забрана сегодня",
+                    "type": "integer"
+                },
+                "claimed_today": {
+                    "type": "boolean"
+                },
+                "next_claim_in": {
+                    "description": "NextClaimIn — секунд до начала следующего календарного дня",
+                    "type": "integer"
+                },
+                "next_reward": {
+                    "description": "NextReward — награда за следующий день серии",
+                    "type": "integer"
+                },
+                "streak": {
+                    "description": "Streak — количество дней подряд, за которые забрана награда",
+                    "type": "integer"
+                }
+            }
+        },
+        "indexer.AddToIndexResponse": {
+            "type": "object",
+            "properties": {
+                "added": {
+                    "type": "integer"
+                },
+                "new_value": {
+                    "type": "integer"
+                },
+                "old_value": {
+                    "type": "integer"
+                }
+            }
+        },
+        "indexer.ErrorResponse": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "type": "string"
+                }
+            }
+        },
+        "leaderboard.EntryResponse": {
+            "type": "object",
+            "properties": {
+                "level": {
+                    "type": "integer"
+                },
+                "rank": {
+                    "type": "integer"
+                },
+                "username": {
+                    "type": "string"
+                },
+                "value": {
+                    "type": "integer"
+                }
+            }
+        },
+        "leaderboard.LeaderboardResponse": {
+            "type": "object",
+            "properties": {
+                "board": {
+                    "type": "string"
+                },
+                "entries": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/leaderboard.EntryResponse"
+                    }
+                },
+                "me": {
+                    "description": "Me — место текущего пользователя, даже если он не попал в топ.\nnil, если пользователь появился после последнего пересчета рейтинга",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/leaderboard.EntryResponse"
+                        }
+                    ]
+                },
+                "scope": {
+                    "type": "string"
+                }
+            }
+        },
+        "ledger.LedgerRepo": {
+            "type": "object",
+            "properties": {
+                "amount": {
+                    "type": "integer"
+                },
+                "balance_after": {
+                    "type": "integer"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "reason": {
+                    "type": "string"
+                },
+                "source_id": {
+                    "type": "integer"
+                },
+                "source_type": {
+                    "type": "string"
+                }
+            }
+        },
+        "ledger.TransactionsResponse": {
+            "type": "object",
+            "properties": {
+                "items": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/ledger.LedgerRepo"
+                    }
+                },
+                "next_cursor": {
+                    "type": "integer"
+                }
+            }
+        },
+        "referral.FriendResponse": {
+            "type": "object",
+            "properties": {
+                "earned": {
+                    "description": "Earned — сколько пригласивший получил с дохода этого друга",
+                    "type": "integer"
+                },
+                "joined_at": {
+                    "type": "string"
+                },
+                "level": {
+                    "type": "integer"
+                },
+                "username": {
+                    "type": "string"
+                }
+            }
+        },
+        "referral.ReferralsResponse": {
+            "type": "object",
+            "properties": {
+                "code": {
+                    "type": "string"
+                },
+                "count": {
+                    "type": "integer"
+                },
+                "friends": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/referral.FriendResponse"
+                    }
+                },
+                "link": {
+                    "description": "Link заполняется, если задан TELEGRAM_APP_URL",
+                    "type": "string"
+                },
+                "share_percent": {
+                    "description": "SharePercent — доля дохода друзей, которую получает пригласивший",
+                    "type": "integer"
+                },
+                "start_param": {
+                    "type": "string"
+                },
+                "total_earned": {
+                    "type": "integer"
+                }
+            }
+        },
+        "tasks.CreateTaskRequest": {
+            "type": "object",
+            "properties": {
+                "channel": {
+                    "type": "string"
+                },
+                "description": {
+                    "type": "string"
+                },
+                "reward": {
+                    "type": "integer"
+                },
+                "target": {
+                    "type": "integer"
+                },
+                "title": {
+                    "type": "string"
+                },
+                "type": {
+                    "type": "string"
+                },
+                "worker_type": {
+                    "type": "string"
+                }
+            }
+        },
+        "tasks.TaskRepo": {
+            "type": "object",
+            "properties": {
+                "channel": {
+                    "description": "Channel — @username канала для задания join_channel",
+                    "type": "string"
+                },
+                "description": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "is_active": {
+                    "type": "boolean"
+                },
+                "reward": {
+                    "type": "integer"
+                },
+                "target": {
+                    "type": "integer"
+                },
+                "title": {
+                    "type": "string"
+                },
+                "type": {
+                    "type": "string"
+                },
+                "worker_type": {
+                    "description": "WorkerType ограничивает задание buy_worker типом работника, например army",
+                    "type": "string"
+                }
+            }
+        },
+        "tasks.TaskResponse": {
+            "type": "object",
+            "properties": {
+                "channel": {
+                    "type": "string"
+                },
+                "claimed": {
+                    "type": "boolean"
+                },
+                "completed": {
+                    "description": "Completed — условие выполнено. Для join_channel проверяется только при получении награды",
+                    "type": "boolean"
+                },
+                "description": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "progress": {
+                    "type": "integer"
+                },
+                "reward": {
+                    "type": "integer"
+                },
+                "target": {
+                    "type": "integer"
+                },
+                "title": {
+                    "type": "string"
+                },
+                "type": {
+                    "type": "string"
+                }
+            }
+        },
+        "user.BoostStateResponse": {
             "type": "object",
             "properties": {
-                "can_buy": {
-                    "type": "boolean"
+                "multiplier": {
+                    "type": "integer"
                 },
+                "remaining_seconds": {
+                    "type": "integer"
+                }
+            }
+        },
+        "user.EquippedItemResponse": {
+            "type": "object",
+            "properties": {
                 "description": {
                     "type": "string"
                 },
                 "id": {
                     "type": "integer"
                 },
-                "is_active": {
-                    "type": "boolean"
-                },
-                "is_bought": {
-                    "type": "boolean"
-                },
                 "name": {
                     "type": "string"
                 },
@@ -640,9 +2456,6 @@ const docTemplate = `{
                 "plus_energy": {
                     "type": "integer"
                 },
-                "price": {
-                    "type": "integer"
-                },
                 "rarity": {
                     "type": "string"
                 },
@@ -654,48 +2467,125 @@ const docTemplate = `{
                 }
             }
         },
-        "indexer.AddToIndexResponse": {
+        "user.LevelProgressResponse": {
             "type": "object",
             "properties": {
-                "added": {
+                "current_level_xp": {
+                    "description": "CurrentLevelXP — опыт, с которого начинается текущий уровень",
                     "type": "integer"
                 },
-                "new_value": {
+                "level": {
                     "type": "integer"
                 },
-                "old_value": {
+                "next_level": {
+                    "description": "NextLevel — следующий уровень, nil если достигнут максимальный",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/user.LevelRepo"
+                        }
+                    ]
+                },
+                "progress": {
+                    "description": "Progress — доля пройденного пути до следующего уровня от 0 до 1",
+                    "type": "number"
+                },
+                "xp": {
                     "type": "integer"
                 }
             }
         },
-        "indexer.ErrorResponse": {
+        "user.LevelRepo": {
+            "type": "object",
+            "properties": {
+                "level": {
+                    "type": "integer"
+                },
+                "max_energy_bonus": {
+                    "type": "integer"
+                },
+                "reward": {
+                    "type": "integer"
+                },
+                "tap_profit_bonus": {
+                    "type": "integer"
+                },
+                "xp_required": {
+                    "type": "integer"
+                }
+            }
+        },
+        "user.OfflineEarningsResponse": {
+            "type": "object",
+            "properties": {
+                "away_seconds": {
+                    "type": "integer"
+                },
+                "capped": {
+                    "description": "Capped — отсутствие длилось дольше лимита, и доход начислен только за лимит",
+                    "type": "boolean"
+                },
+                "earned": {
+                    "type": "integer"
+                }
+            }
+        },
+        "user.OutOfEnergyResponse": {
             "type": "object",
             "properties": {
+                "code": {
+                    "type": "string"
+                },
                 "error": {
                     "type": "string"
+                },
+                "next_energy_in": {
+                    "type": "integer"
                 }
             }
         },
-        "user.UserRepo": {
+        "user.TapBatchRequest": {
             "type": "object",
             "properties": {
-                "balance": {
+                "count": {
                     "type": "integer"
                 },
-                "body": {
-                    "type": "string"
+                "finished_at": {
+                    "type": "integer"
+                },
+                "started_at": {
+                    "type": "integer"
+                }
+            }
+        },
+        "user.TapBatchResponse": {
+            "type": "object",
+            "properties": {
+                "applied": {
+                    "type": "integer"
+                },
+                "balance": {
+                    "type": "integer"
                 },
                 "energy": {
                     "type": "integer"
                 },
-                "foot": {
-                    "type": "string"
+                "profit": {
+                    "type": "integer"
+                }
+            }
+        },
+        "user.UserRepo": {
+            "type": "object",
+            "properties": {
+                "balance": {
+                    "type": "integer"
                 },
-                "hand": {
-                    "type": "string"
+                "energy": {
+                    "type": "integer"
                 },
-                "head": {
-                    "type": "string"
+                "gems": {
+                    "description": "Gems — премиальная валюта",
+                    "type": "integer"
                 },
                 "id": {
                     "type": "integer"
@@ -706,9 +2596,6 @@ const docTemplate = `{
                 "last_restoration": {
                     "type": "string"
                 },
-                "legs": {
-                    "type": "string"
-                },
                 "level": {
                     "type": "integer"
                 },
@@ -726,6 +2613,9 @@ const docTemplate = `{
                 },
                 "username": {
                     "type": "string"
+                },
+                "xp": {
+                    "type": "integer"
                 }
             }
         },
@@ -736,22 +2626,30 @@ const docTemplate = `{
                     "type": "integer"
                 },
                 "body": {
-                    "type": "string"
+                    "$ref": "#/definitions/user.EquippedItemResponse"
                 },
                 "energy": {
                     "type": "integer"
                 },
                 "foot": {
-                    "type": "string"
+                    "$ref": "#/definitions/user.EquippedItemResponse"
+                },
+                "gems": {
+                    "type": "integer"
                 },
                 "hand": {
-                    "type": "string"
+                    "$ref": "#/definitions/user.EquippedItemResponse"
                 },
                 "head": {
-                    "type": "string"
+                    "description": "Head, Body, Legs, Foot и Hand — надетая одежда, nil если слот пуст",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/user.EquippedItemResponse"
+                        }
+                    ]
                 },
                 "legs": {
-                    "type": "string"
+                    "$ref": "#/definitions/user.EquippedItemResponse"
                 },
                 "level": {
                     "type": "integer"
@@ -765,8 +2663,158 @@ const docTemplate = `{
                 "profit_per_hour": {
                     "type": "integer"
                 },
+                "regen_boost": {
+                    "$ref": "#/definitions/user.BoostStateResponse"
+                },
+                "tap_boost": {
+                    "description": "TapBoost и RegenBoost — действующие бусты, nil если буст не активен",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/user.BoostStateResponse"
+                        }
+                    ]
+                },
                 "username": {
                     "type": "string"
+                },
+                "welcome_back": {
+                    "$ref": "#/definitions/user.OfflineEarningsResponse"
+                }
+            }
+        },
+        "workers.CategoryRepo": {
+            "type": "object",
+            "properties": {
+                "name": {
+                    "type": "string"
+                },
+                "slug": {
+                    "type": "string"
+                }
+            }
+        },
+        "workers.ComboItemResponse": {
+            "type": "object",
+            "properties": {
+                "done": {
+                    "type": "boolean"
+                },
+                "level": {
+                    "type": "integer"
+                },
+                "min_level": {
+                    "type": "integer"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "url_image": {
+                    "type": "string"
+                },
+                "worker_id": {
+                    "type": "integer"
+                }
+            }
+        },
+        "workers.ProfitDriftResponse": {
+            "type": "object",
+            "properties": {
+                "expected": {
+                    "type": "integer"
+                },
+                "fixed": {
+                    "description": "Fixed — доход в час исправлен этим запросом",
+                    "type": "boolean"
+                },
+                "stored": {
+                    "type": "integer"
+                },
+                "user_id": {
+                    "type": "integer"
+                }
+            }
+        },
+        "workers.ReconcileFailureResponse": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "type": "string"
+                },
+                "user_id": {
+                    "type": "integer"
+                }
+            }
+        },
+        "workers.ReconcileProfitResponse": {
+            "type": "object",
+            "properties": {
+                "dry_run": {
+                    "type": "boolean"
+                },
+                "failed": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/workers.ReconcileFailureResponse"
+                    }
+                },
+                "users": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/workers.ProfitDriftResponse"
+                    }
+                }
+            }
+        },
+        "workers.UpgradeAllRequest": {
+            "type": "object",
+            "properties": {
+                "budget": {
+                    "description": "Budget — сколько монет можно потратить, без него тратится весь баланс",
+                    "type": "integer"
+                }
+            }
+        },
+        "workers.UpgradeAllResponse": {
+            "type": "object",
+            "properties": {
+                "balance": {
+                    "type": "integer"
+                },
+                "profit_per_hour": {
+                    "type": "integer"
+                },
+                "spent": {
+                    "type": "integer"
+                },
+                "upgrades": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/workers.UpgradeSummary"
+                    }
+                }
+            }
+        },
+        "workers.UpgradeSummary": {
+            "type": "object",
+            "properties": {
+                "completes_in": {
+                    "description": "CompletesIn — через сколько секунд применится улучшение, 0 — применено сразу",
+                    "type": "integer"
+                },
+                "cost": {
+                    "type": "integer"
+                },
+                "level": {
+                    "type": "integer"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "profit_delta": {
+                    "type": "integer"
+                },
+                "worker_id": {
+                    "type": "integer"
                 }
             }
         },
@@ -782,25 +2830,74 @@ const docTemplate = `{
                 "description": {
                     "type": "string"
                 },
+                "finish_gems": {
+                    "type": "integer"
+                },
                 "id": {
                     "type": "integer"
                 },
                 "level": {
                     "type": "integer"
                 },
+                "locked": {
+                    "description": "Locked — работник еще не открыт, UnlockCondition описывает невыполненные условия",
+                    "type": "boolean"
+                },
                 "name": {
                     "type": "string"
                 },
                 "profit": {
                     "type": "integer"
                 },
+                "unlock_condition": {
+                    "type": "string"
+                },
+                "upgrade_completes_in": {
+                    "type": "integer"
+                },
+                "upgrading_to_level": {
+                    "description": "UpgradingToLevel — уровень, до которого идет улучшение, UpgradeCompletesIn — сколько секунд до его завершения,\nFinishGems — сколько кристаллов стоит завершить его сейчас",
+                    "type": "integer"
+                },
                 "url_image": {
                     "type": "string"
                 }
             }
+        },
+        "workers.WorkerComboResponse": {
+            "type": "object",
+            "properties": {
+                "bonus_percent": {
+                    "type": "integer"
+                },
+                "completed": {
+                    "type": "boolean"
+                },
+                "description": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "items": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/workers.ComboItemResponse"
+                    }
+                },
+                "name": {
+                    "type": "string"
+                }
+            }
         }
     },
     "securityDefinitions": {
+        "AdminAuth": {
+            "description": "Токен администратора из ADMIN_TOKEN",
+            "type": "apiKey",
+            "name": "X-Admin-Token",
+            "in": "header"
+        },
         "TelegramAuth": {
             "description": "Данные инициализации Telegram WebApp",
             "type": "apiKey",
@@ -5,16 +5,25 @@ import (
 	"github.com/labstack/echo/v4/middleware"
 	"github.com/swaggo/echo-swagger"
 	_ "api/docs"
+	"api/src/battles"
+	"api/src/boosts"
+	"api/src/daily"
 	"api/src/indexer"
+	"api/src/leaderboard"
+	"api/src/ledger"
+	"api/src/referral"
+	"api/src/tasks"
 	"api/src/user"
 	"api/src/workers"
 	"api/src/clothes"
 	"api/db"
 	"api/src/core/config"
+	"api/src/core/economy"
 	"api/src/core/loger"
 	customMiddleware "api/src/core/middleware"
 	"go.uber.org/zap"
 	"net/http"
+	"time"
 )
 
 // @title API Documentation
@@ -27,6 +36,11 @@ import (
 // @in header
 // @name X-Telegram-Init-Data
 // @description Данные инициализации Telegram WebApp
+
+// @securityDefinitions.apikey AdminAuth
+// @in header
+// @name X-Admin-Token
+// @description Токен администратора из ADMIN_TOKEN
 func main() {
 	// Инициализация логгера
 	if err := loger.InitLogger("logs/app.log"); err != nil {
@@ -38,7 +52,10 @@ func main() {
 	if err != nil {
 		loger.Logger.Fatal("Ошибка загрузки конфигурации", zap.Error(err))
 	}
-	loger.Logger.Info("Конфигурация загружена", zap.Any("config", config))
+	loger.Logger.Info("Конфигурация загружена", zap.Object("config", config))
+
+	// Настройки экономики перечитываются при изменении файла
+	economy.Init(config.ECONOMY_CONFIG_PATH, 10*time.Second)
 	
 	e := echo.New()
 	
@@ -96,19 +113,59 @@ func main() {
 	// Setup routes
 	indexer.SetupIndexer(e, database)
 	
+	// Журнал операций, через который проходят все изменения баланса
+	ledgerRepo := ledger.NewLedgerRepository(database)
+	ledgerService := ledger.NewLedgerService(ledgerRepo)
+	
+	// Реферальная программа
+	referralRepo := referral.NewReferralRepository(database)
+	referralService := referral.NewReferralService(referralRepo, ledgerService, config)
+	referral.RegisterRoutes(e, referralService, config)
+	
+	// Задания получают прогресс из игровых событий
+	tasksRepo := tasks.NewTasksRepository(database)
+	tasksService := tasks.NewTasksService(tasksRepo, ledgerService, config)
+	tasksService.SubscribeEvents()
+	tasks.RegisterRoutes(e, tasksService, config)
+	
 	// Создаем сервис пользователей
 	userRepo := user.NewUserRepository(database)
-	userService := user.NewService(userRepo)
-	user.SetupUser(e, database, config)
+	userService := user.NewService(userRepo, ledgerService, referralService)
+	user.SetupUser(e, userService, config)
+	
+	// Временные бусты тапов и энергии
+	boostsRepo := boosts.NewBoostsRepository(database)
+	boostsService := boosts.NewBoostsService(boostsRepo, userService, ledgerService)
+	boosts.RegisterRoutes(e, boostsService, config)
+	
+	// Ежедневные награды и комбо дня, которое выбирается при наступлении нового дня
+	dailyRepo := daily.NewDailyRepository(database)
+	dailyService := daily.NewDailyService(dailyRepo, ledgerService)
+	dailyService.SubscribeEvents()
+	dailyService.StartComboScheduler(time.Minute)
+	daily.RegisterRoutes(e, dailyService, config)
+	
+	// Рейтинг игроков пересчитывается раз в минуту
+	leaderboardRepo := leaderboard.NewLeaderboardRepository(database)
+	leaderboardService := leaderboard.NewLeaderboardService(leaderboardRepo)
+	leaderboardService.StartRefresher(time.Minute)
+	leaderboard.RegisterRoutes(e, leaderboardService, config)
+	
+	// Бои армий, сила армии пересчитывается при покупке воинов
+	battlesRepo := battles.NewBattlesRepository(database)
+	battlesService := battles.NewBattlesService(battlesRepo, ledgerService)
+	battlesService.SubscribeEvents()
+	battles.RegisterRoutes(e, battlesService, config)
 	
 	// Создаем сервис для работников
 	workerRepo := workers.NewWorkerRepository(database)
-	workerService := workers.NewWorkerService(workerRepo, userService)
+	workerService := workers.NewWorkerService(workerRepo, userService, ledgerService)
+	workerService.SubscribeEvents()
 	workers.RegisterRoutes(e, workerService, config)
 
 	// Создаем сервис для одежды
 	clothesRepo := clothes.NewClothesRepository(database)
-	clothesService := clothes.NewClothesService(clothesRepo, userService)
+	clothesService := clothes.NewClothesService(clothesRepo, userService, ledgerService)
 	clothes.RegisterRoutes(e, clothesService, config)
 	
 	loger.Logger.Info("Сервер запускается", zap.String("port", config.PORT))
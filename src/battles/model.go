@@ -0,0 +1,64 @@
+package battles
+
+import "time"
+
+// Стороны боя
+const (
+	RoleAttacker = "attacker"
+	RoleDefender = "defender"
+)
+
+// armyCategory — категория работников, из которых складывается сила армии
+const armyCategory = "army"
+
+type FighterRepo struct {
+	ID                int
+	Username          string
+	ProfitPerHour     int
+	ProfitRemainder   int64
+	LastProfitPerHour time.Time
+	ArmyAttack        int64
+	ArmyDefense       int64
+	LastRaidAt        *time.Time
+	LastRaidedAt      *time.Time
+}
+
+type BattleRepo struct {
+	ID         int
+	AttackerID int
+	DefenderID int
+	Attack     int64
+	Defense    int64
+	WinnerID   int
+	Stolen     int64
+}
+
+type PowerResponse struct {
+	Attack  int64 `json:"attack"`
+	Defense int64 `json:"defense"`
+	// NextRaidIn — через сколько секунд можно напасть снова, 0 если уже можно
+	NextRaidIn int64 `json:"next_raid_in"`
+}
+
+type BattleResponse struct {
+	ID               int    `json:"id"`
+	Role             string `json:"role"`
+	OpponentUsername string `json:"opponent_username"`
+	Attack           int64  `json:"attack"`
+	Defense          int64  `json:"defense"`
+	Won              bool   `json:"won"`
+	// Stolen — сколько монет забрал победитель
+	Stolen    int64     `json:"stolen"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type BattlesResponse struct {
+	Items      []BattleResponse `json:"items"`
+	NextCursor *int             `json:"next_cursor"`
+}
+
+// CooldownResponse возвращается, когда нападать еще рано
+type CooldownResponse struct {
+	Error      string `json:"error"`
+	NextRaidIn int64  `json:"next_raid_in"`
+}
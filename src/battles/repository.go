@@ -0,0 +1,174 @@
+package battles
+
+import (
+	"api/db"
+	"context"
+	"database/sql"
+	"math/rand"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+const fighterColumns = "id, username, profit_per_hour, profit_remainder, last_profit_per_hour, army_attack, army_defense, last_raid_at, last_raided_at"
+
+type BattlesRepository struct {
+	db *sql.DB
+}
+
+func NewBattlesRepository(db *sql.DB) *BattlesRepository {
+	return &BattlesRepository{db: db}
+}
+
+// WithTx выполняет fn в одной транзакции базы данных
+func (r *BattlesRepository) WithTx(ctx context.Context, fn func(tx *sql.Tx) error) error {
+	return db.WithTx(ctx, r.db, fn)
+}
+
+func scanFighter(row interface{ Scan(dest ...any) error }) (*FighterRepo, error) {
+	var f FighterRepo
+	var lastRaidAt, lastRaidedAt sql.NullTime
+	err := row.Scan(&f.ID, &f.Username, &f.ProfitPerHour, &f.ProfitRemainder, &f.LastProfitPerHour, &f.ArmyAttack, &f.ArmyDefense, &lastRaidAt, &lastRaidedAt)
+	if err != nil {
+		return nil, err
+	}
+	if lastRaidAt.Valid {
+		f.LastRaidAt = &lastRaidAt.Time
+	}
+	if lastRaidedAt.Valid {
+		f.LastRaidedAt = &lastRaidedAt.Time
+	}
+	return &f, nil
+}
+
+func (r *BattlesRepository) GetFighter(ctx context.Context, tg_id int64) (*FighterRepo, error) {
+	return scanFighter(r.db.QueryRowContext(ctx, "SELECT "+fighterColumns+" FROM users WHERE tg_id = $1", tg_id))
+}
+
+// LockFighters блокирует строки участников боя в порядке id, чтобы встречные нападения не взаимоблокировались
+func (r *BattlesRepository) LockFighters(ctx context.Context, tx *sql.Tx, ids ...int) (map[int]*FighterRepo, error) {
+	rows, err := tx.QueryContext(ctx, "SELECT "+fighterColumns+" FROM users WHERE id = ANY($1) ORDER BY id FOR UPDATE", pq.Array(ids))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	fighters := map[int]*FighterRepo{}
+	for rows.Next() {
+		f, err := scanFighter(rows)
+		if err != nil {
+			return nil, err
+		}
+		fighters[f.ID] = f
+	}
+	return fighters, rows.Err()
+}
+
+// opponentFilter — соперники с армией, чья защита в диапазоне [$3, $4], кроме нападающего и тех, кто под защитой
+// после недавнего нападения. Диапазон по army_defense использует индекс users_army_defense_idx
+const opponentFilter = `FROM users
+		WHERE army_defense BETWEEN $3 AND $4
+			AND army_defense > 0
+			AND id <> $1
+			AND (last_raided_at IS NULL OR last_raided_at < NOW() - $2 * INTERVAL '1 second')`
+
+// FindOpponent подбирает случайного соперника, чья защита отличается от attack не больше чем на rangePercent процентов.
+// Если таких нет, возвращает sql.ErrNoRows
+func (r *BattlesRepository) FindOpponent(ctx context.Context, tx *sql.Tx, attackerID int, attack int64, rangePercent int, shield time.Duration) (int, error) {
+	low := attack - attack*int64(rangePercent)/100
+	high := attack + attack*int64(rangePercent)/100
+	args := []any{attackerID, int64(shield.Seconds()), low, high}
+
+	var count int64
+	if err := tx.QueryRowContext(ctx, "SELECT COUNT(*) "+opponentFilter, args...).Scan(&count); err != nil {
+		return 0, err
+	}
+	if count == 0 {
+		return 0, sql.ErrNoRows
+	}
+	var id int
+	err := tx.QueryRowContext(ctx,
+		"SELECT id "+opponentFilter+" ORDER BY army_defense, id OFFSET $5 LIMIT 1",
+		append(args, rand.Int63n(count))...,
+	).Scan(&id)
+	if err != nil {
+		return 0, err
+	}
+	return id, nil
+}
+
+// SetProfitStart переносит начало ненакопленного пассивного дохода пользователя
+func (r *BattlesRepository) SetProfitStart(ctx context.Context, tx *sql.Tx, userID int, start time.Time) error {
+	_, err := tx.ExecContext(ctx, "UPDATE users SET last_profit_per_hour = $1 WHERE id = $2", start, userID)
+	return err
+}
+
+func (r *BattlesRepository) CreateBattle(ctx context.Context, tx *sql.Tx, battle BattleRepo) (int, error) {
+	var id int
+	err := tx.QueryRowContext(ctx,
+		`INSERT INTO battles (attacker_id, defender_id, attack, defense, winner_id, stolen)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id`,
+		battle.AttackerID, battle.DefenderID, battle.Attack, battle.Defense, battle.WinnerID, battle.Stolen,
+	).Scan(&id)
+	if err != nil {
+		return 0, err
+	}
+	return id, nil
+}
+
+// MarkRaid запоминает время нападения для перезарядки нападающего и защиты соперника
+func (r *BattlesRepository) MarkRaid(ctx context.Context, tx *sql.Tx, attackerID int, defenderID int) error {
+	_, err := tx.ExecContext(ctx,
+		`UPDATE users SET
+			last_raid_at = CASE WHEN id = $1 THEN NOW() ELSE last_raid_at END,
+			last_raided_at = CASE WHEN id = $2 THEN NOW() ELSE last_raided_at END
+		WHERE id IN ($1, $2)`,
+		attackerID, defenderID,
+	)
+	return err
+}
+
+// GetBattles возвращает бои пользователя с обеих сторон, новые первыми, с id меньше cursor
+func (r *BattlesRepository) GetBattles(ctx context.Context, userID int, cursor int, limit int) ([]BattleResponse, error) {
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT b.id,
+			CASE WHEN b.attacker_id = $1 THEN 'attacker' ELSE 'defender' END,
+			o.username, b.attack, b.defense, b.winner_id = $1, b.stolen, b.created_at
+		FROM battles b
+		JOIN users o ON o.id = CASE WHEN b.attacker_id = $1 THEN b.defender_id ELSE b.attacker_id END
+		WHERE (b.attacker_id = $1 OR b.defender_id = $1) AND ($2 = 0 OR b.id < $2)
+		ORDER BY b.id DESC
+		LIMIT $3`,
+		userID, cursor, limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	battles := []BattleResponse{}
+	for rows.Next() {
+		var b BattleResponse
+		if err := rows.Scan(&b.ID, &b.Role, &b.OpponentUsername, &b.Attack, &b.Defense, &b.Won, &b.Stolen, &b.CreatedAt); err != nil {
+			return nil, err
+		}
+		battles = append(battles, b)
+	}
+	return battles, rows.Err()
+}
+
+// RecalculatePower пересчитывает атаку и защиту армии пользователя по уровням его воинов
+func (r *BattlesRepository) RecalculatePower(ctx context.Context, tx *sql.Tx, userID int) error {
+	_, err := tx.ExecContext(ctx,
+		`UPDATE users SET army_attack = p.attack, army_defense = p.defense
+		FROM (
+			SELECT COALESCE(SUM(wu.attack), 0) AS attack, COALESCE(SUM(wu.defense), 0) AS defense
+			FROM user_workers uw
+			JOIN workers w ON w.id = uw.id_worker AND w.type = $2
+			JOIN workers_upgrade wu ON wu.id_worker = uw.id_worker AND wu.level = uw.level
+			WHERE uw.id_user = $1
+		) p
+		WHERE users.id = $1`,
+		userID, armyCategory,
+	)
+	return err
+}
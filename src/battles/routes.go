@@ -0,0 +1,73 @@
+package battles
+
+import (
+	"api/src/core/config"
+	"api/src/middleware"
+	"github.com/labstack/echo/v4"
+)
+
+type BattlesHandler struct {
+	service *BattlesService
+	config  *config.Config
+}
+
+func NewBattlesHandler(service *BattlesService, config *config.Config) *BattlesHandler {
+	return &BattlesHandler{service: service, config: config}
+}
+
+func RegisterRoutes(e *echo.Echo, service *BattlesService, config *config.Config) {
+	handler := NewBattlesHandler(service, config)
+
+	battlesGroup := e.Group("/battles")
+	battlesGroup.Use(middleware.TelegramAuth(middleware.TelegramAuthConfig{
+		BotToken: handler.config.TELEGRAM_BOT_TOKEN,
+	}))
+	battlesGroup.GET("", handler.GetBattles)
+	battlesGroup.GET("/power", handler.GetPower)
+	battlesGroup.POST("/raid", handler.Raid)
+}
+
+// @Summary История боев
+// @Description Возвращает бои пользователя как нападающего и как защищающегося, новые первыми
+// @Tags battles
+// @Accept json
+// @Produce json
+// @Param cursor query int false "ID боя, после которого продолжить"
+// @Param limit query int false "Размер страницы, не больше 100"
+// @Success 200 {object} BattlesResponse
+// @Failure 400 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /battles [get]
+// @Security TelegramAuth
+func (h *BattlesHandler) GetBattles(c echo.Context) error {
+	return h.service.GetBattles(c)
+}
+
+// @Summary Сила армии
+// @Description Возвращает атаку и защиту армии пользователя и время до следующего нападения
+// @Tags battles
+// @Accept json
+// @Produce json
+// @Success 200 {object} PowerResponse
+// @Failure 500 {object} map[string]string
+// @Router /battles/power [get]
+// @Security TelegramAuth
+func (h *BattlesHandler) GetPower(c echo.Context) error {
+	return h.service.GetPower(c)
+}
+
+// @Summary Напасть на игрока
+// @Description Нападает на игрока с похожей силой армии. Победитель забирает часть ненакопленного пассивного дохода проигравшего
+// @Tags battles
+// @Accept json
+// @Produce json
+// @Success 200 {object} BattleResponse
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 429 {object} CooldownResponse
+// @Failure 500 {object} map[string]string
+// @Router /battles/raid [post]
+// @Security TelegramAuth
+func (h *BattlesHandler) Raid(c echo.Context) error {
+	return h.service.Raid(c)
+}
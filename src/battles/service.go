@@ -0,0 +1,276 @@
+package battles
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+
+	"api/src/core/economy"
+	"api/src/core/events"
+	"api/src/core/loger"
+	"api/src/ledger"
+	"api/src/middleware"
+	"api/src/user"
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+)
+
+const (
+	defaultPageSize = 50
+	maxPageSize     = 100
+)
+
+var (
+	ErrNoArmy     = errors.New("нет армии для нападения")
+	ErrNoOpponent = errors.New("соперник не найден")
+	ErrCooldown   = errors.New("армия еще не готова к нападению")
+)
+
+type BattlesService struct {
+	repo   *BattlesRepository
+	ledger *ledger.LedgerService
+}
+
+func NewBattlesService(repo *BattlesRepository, ledger *ledger.LedgerService) *BattlesService {
+	return &BattlesService{repo: repo, ledger: ledger}
+}
+
+// SubscribeEvents пересчитывает силу армии после покупки или улучшения воина
+func (s *BattlesService) SubscribeEvents() {
+	events.Subscribe(events.WorkerBought, func(ctx context.Context, tx *sql.Tx, event events.Event) error {
+		if event.Attrs["worker_type"] != armyCategory {
+			return nil
+		}
+		return s.repo.RecalculatePower(ctx, tx, event.UserID)
+	})
+}
+
+// nextRaidIn возвращает время до следующего нападения, 0 если нападать уже можно
+func nextRaidIn(f *FighterRepo, now time.Time, rules *economy.Rules) time.Duration {
+	if f.LastRaidAt == nil {
+		return 0
+	}
+	left := f.LastRaidAt.Add(rules.BattleCooldown()).Sub(now)
+	if left < 0 {
+		return 0
+	}
+	return left
+}
+
+func seconds(d time.Duration) int64 {
+	return int64((d + time.Second - 1) / time.Second)
+}
+
+// attackerWins разыгрывает исход боя: шанс нападающего пропорционален его доле в общей силе
+func attackerWins(attack int64, defense int64) bool {
+	if attack+defense == 0 {
+		return rand.Intn(2) == 0
+	}
+	return rand.Int63n(attack+defense) < attack
+}
+
+// steal забирает у проигравшего долю ненакопленного пассивного дохода.
+// Начало периода дохода сдвигается вперед на время, за которое набежала бы добыча
+func (s *BattlesService) steal(ctx context.Context, tx *sql.Tx, loser *FighterRepo, now time.Time, rules *economy.Rules) (int64, error) {
+	unclaimed, start := user.UnclaimedProfit(loser.ProfitPerHour, loser.ProfitRemainder, loser.LastProfitPerHour, now)
+	stolen := unclaimed * int64(rules.BattleStealPercent) / 100
+	if stolen > rules.BattleMaxSteal {
+		stolen = rules.BattleMaxSteal
+	}
+	if stolen <= 0 {
+		return 0, nil
+	}
+	hourMs := time.Hour.Milliseconds()
+	shiftMs := (stolen*hourMs + int64(loser.ProfitPerHour) - 1) / int64(loser.ProfitPerHour)
+	if err := s.repo.SetProfitStart(ctx, tx, loser.ID, start.Add(time.Duration(shiftMs)*time.Millisecond)); err != nil {
+		return 0, err
+	}
+	return stolen, nil
+}
+
+func (s *BattlesService) GetPower(c echo.Context) error {
+	telegramUser := c.Get("telegram_user").(*middleware.TelegramUser)
+	fighter, err := s.repo.GetFighter(c.Request().Context(), telegramUser.ID)
+	if err != nil {
+		loger.Logger.Error("Ошибка при получении пользователя",
+			zap.Error(err),
+			zap.Int64("user_id", telegramUser.ID))
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Ошибка при получении пользователя"})
+	}
+	return c.JSON(http.StatusOK, PowerResponse{
+		Attack:     fighter.ArmyAttack,
+		Defense:    fighter.ArmyDefense,
+		NextRaidIn: seconds(nextRaidIn(fighter, time.Now(), economy.Get())),
+	})
+}
+
+// Raid нападает на соперника с похожей силой. Победитель забирает часть
+// ненакопленного пассивного дохода проигравшего
+func (s *BattlesService) Raid(c echo.Context) error {
+	ctx := c.Request().Context()
+	telegramUser := c.Get("telegram_user").(*middleware.TelegramUser)
+	rules := economy.Get()
+
+	attacker, err := s.repo.GetFighter(ctx, telegramUser.ID)
+	if err != nil {
+		loger.Logger.Error("Ошибка при получении пользователя",
+			zap.Error(err),
+			zap.Int64("user_id", telegramUser.ID))
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Ошибка при получении пользователя"})
+	}
+
+	var response BattleResponse
+	var cooldown time.Duration
+	err = s.repo.WithTx(ctx, func(tx *sql.Tx) error {
+		if attacker.ArmyAttack <= 0 {
+			return ErrNoArmy
+		}
+		defenderID, err := s.repo.FindOpponent(ctx, tx, attacker.ID, attacker.ArmyAttack, rules.BattleMatchRangePercent, rules.BattleShield())
+		if err == sql.ErrNoRows {
+			return ErrNoOpponent
+		} else if err != nil {
+			return err
+		}
+
+		fighters, err := s.repo.LockFighters(ctx, tx, attacker.ID, defenderID)
+		if err != nil {
+			return err
+		}
+		a, d := fighters[attacker.ID], fighters[defenderID]
+		if a == nil || d == nil {
+			return ErrNoOpponent
+		}
+		now := time.Now()
+		// Состояние могло измениться, пока строки не были заблокированы
+		if cooldown = nextRaidIn(a, now, rules); cooldown > 0 {
+			return ErrCooldown
+		}
+		if a.ArmyAttack <= 0 {
+			return ErrNoArmy
+		}
+		if d.LastRaidedAt != nil && now.Sub(*d.LastRaidedAt) < rules.BattleShield() {
+			return ErrNoOpponent
+		}
+
+		winner, loser := a, d
+		if !attackerWins(a.ArmyAttack, d.ArmyDefense) {
+			winner, loser = d, a
+		}
+		stolen, err := s.steal(ctx, tx, loser, now, rules)
+		if err != nil {
+			return err
+		}
+		battleID, err := s.repo.CreateBattle(ctx, tx, BattleRepo{
+			AttackerID: a.ID,
+			DefenderID: d.ID,
+			Attack:     a.ArmyAttack,
+			Defense:    d.ArmyDefense,
+			WinnerID:   winner.ID,
+			Stolen:     stolen,
+		})
+		if err != nil {
+			return err
+		}
+		_, err = s.ledger.Credit(ctx, tx, ledger.Entry{
+			UserID:     winner.ID,
+			Amount:     stolen,
+			Reason:     ledger.ReasonBattleLoot,
+			SourceType: ledger.SourceBattle,
+			SourceID:   int64(battleID),
+		})
+		if err != nil {
+			return err
+		}
+		if err := s.repo.MarkRaid(ctx, tx, a.ID, d.ID); err != nil {
+			return err
+		}
+
+		response = BattleResponse{
+			ID:               battleID,
+			Role:             RoleAttacker,
+			OpponentUsername: d.Username,
+			Attack:           a.ArmyAttack,
+			Defense:          d.ArmyDefense,
+			Won:              winner.ID == a.ID,
+			Stolen:           stolen,
+			CreatedAt:        now,
+		}
+		return nil
+	})
+	switch {
+	case errors.Is(err, ErrNoArmy):
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	case errors.Is(err, ErrNoOpponent):
+		return c.JSON(http.StatusNotFound, map[string]string{"error": err.Error()})
+	case errors.Is(err, ErrCooldown):
+		return c.JSON(http.StatusTooManyRequests, CooldownResponse{
+			Error:      err.Error(),
+			NextRaidIn: seconds(cooldown),
+		})
+	case err != nil:
+		loger.Logger.Error("Ошибка при нападении",
+			zap.Error(err),
+			zap.Int64("user_id", telegramUser.ID))
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Ошибка при нападении"})
+	}
+
+	loger.Logger.Info("Бой",
+		zap.Int("battle_id", response.ID),
+		zap.Int("attacker_id", attacker.ID),
+		zap.Bool("won", response.Won),
+		zap.Int64("stolen", response.Stolen))
+	return c.JSON(http.StatusOK, response)
+}
+
+func (s *BattlesService) GetBattles(c echo.Context) error {
+	ctx := c.Request().Context()
+	telegramUser := c.Get("telegram_user").(*middleware.TelegramUser)
+
+	var cursor int
+	if v := c.QueryParam("cursor"); v != "" {
+		parsed, err := strconv.Atoi(v)
+		if err != nil || parsed < 0 {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": "Неверный курсор"})
+		}
+		cursor = parsed
+	}
+	limit := defaultPageSize
+	if v := c.QueryParam("limit"); v != "" {
+		parsed, err := strconv.Atoi(v)
+		if err != nil || parsed <= 0 {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": "Неверный лимит"})
+		}
+		limit = parsed
+	}
+	if limit > maxPageSize {
+		limit = maxPageSize
+	}
+
+	fighter, err := s.repo.GetFighter(ctx, telegramUser.ID)
+	if err != nil {
+		loger.Logger.Error("Ошибка при получении пользователя",
+			zap.Error(err),
+			zap.Int64("user_id", telegramUser.ID))
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Ошибка при получении пользователя"})
+	}
+	// Берем на одну запись больше, чтобы понять, есть ли следующая страница
+	battles, err := s.repo.GetBattles(ctx, fighter.ID, cursor, limit+1)
+	if err != nil {
+		loger.Logger.Error("Ошибка при получении истории боев",
+			zap.Error(err),
+			zap.Int("user_id", fighter.ID))
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Ошибка при получении истории боев"})
+	}
+
+	response := BattlesResponse{Items: battles}
+	if len(battles) > limit {
+		response.Items = battles[:limit]
+		next := battles[limit-1].ID
+		response.NextCursor = &next
+	}
+	return c.JSON(http.StatusOK, response)
+}
@@ -0,0 +1,51 @@
+package boosts
+
+import "time"
+
+// Названия бустов в настройках экономики
+const (
+	BoostEnergyRefill = "energy_refill"
+	BoostMultitapX2   = "multitap_x2"
+	BoostMultitapX5   = "multitap_x5"
+	BoostFastRegen    = "fast_regen"
+)
+
+// Эффекты бустов
+const (
+	effectEnergyRefill = "energy_refill"
+	effectTap          = "tap"
+	effectRegen        = "regen"
+)
+
+var boostEffects = map[string]string{
+	BoostEnergyRefill: effectEnergyRefill,
+	BoostMultitapX2:   effectTap,
+	BoostMultitapX5:   effectTap,
+	BoostFastRegen:    effectRegen,
+}
+
+type BoostUserRepo struct {
+	ID              int
+	Energy          int
+	MaxEnergy       int
+	TapBoostUntil   *time.Time
+	RegenBoostUntil *time.Time
+}
+
+type BoostResponse struct {
+	Name            string `json:"name"`
+	Price           int64  `json:"price"`
+	FreePerDay      int    `json:"free_per_day"`
+	FreeLeft        int    `json:"free_left"`
+	DurationSeconds int64  `json:"duration_seconds"`
+	Multiplier      int    `json:"multiplier"`
+	// RemainingSeconds — сколько еще действует буст этого типа, 0 если не активен
+	RemainingSeconds int64 `json:"remaining_seconds"`
+}
+
+type ActivateBoostResponse struct {
+	Boost BoostResponse `json:"boost"`
+	// Paid — сколько монет списано, 0 для бесплатной активации
+	Paid    int64 `json:"paid"`
+	Balance int64 `json:"balance"`
+}
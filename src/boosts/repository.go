@@ -0,0 +1,121 @@
+package boosts
+
+import (
+	"api/db"
+	"context"
+	"database/sql"
+	"time"
+)
+
+type BoostsRepository struct {
+	db *sql.DB
+}
+
+func NewBoostsRepository(db *sql.DB) *BoostsRepository {
+	return &BoostsRepository{db: db}
+}
+
+// WithTx выполняет fn в одной транзакции базы данных
+func (r *BoostsRepository) WithTx(ctx context.Context, fn func(tx *sql.Tx) error) error {
+	return db.WithTx(ctx, r.db, fn)
+}
+
+type queryer interface {
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+}
+
+func getUser(ctx context.Context, q queryer, query string, tg_id int64) (*BoostUserRepo, error) {
+	var user BoostUserRepo
+	var tapBoostUntil, regenBoostUntil sql.NullTime
+	err := q.QueryRowContext(ctx, query, tg_id).Scan(&user.ID, &user.Energy, &user.MaxEnergy, &tapBoostUntil, &regenBoostUntil)
+	if err != nil {
+		return nil, err
+	}
+	if tapBoostUntil.Valid {
+		user.TapBoostUntil = &tapBoostUntil.Time
+	}
+	if regenBoostUntil.Valid {
+		user.RegenBoostUntil = &regenBoostUntil.Time
+	}
+	return &user, nil
+}
+
+func (r *BoostsRepository) GetUser(ctx context.Context, tg_id int64) (*BoostUserRepo, error) {
+	return getUser(ctx, r.db, "SELECT id, energy, max_energy, tap_boost_until, regen_boost_until FROM users WHERE tg_id = $1", tg_id)
+}
+
+// LockUser читает состояние бустов пользователя и блокирует его строку до конца транзакции
+func (r *BoostsRepository) LockUser(ctx context.Context, tx *sql.Tx, tg_id int64) (*BoostUserRepo, error) {
+	return getUser(ctx, tx, "SELECT id, energy, max_energy, tap_boost_until, regen_boost_until FROM users WHERE tg_id = $1 FOR UPDATE", tg_id)
+}
+
+// CountFreeActivations возвращает число бесплатных активаций каждого буста за день
+func (r *BoostsRepository) CountFreeActivations(ctx context.Context, userID int, day time.Time) (map[string]int, error) {
+	return countFreeActivations(ctx, r.db, userID, day)
+}
+
+func (r *BoostsRepository) CountFreeActivationsTx(ctx context.Context, tx *sql.Tx, userID int, day time.Time) (map[string]int, error) {
+	return countFreeActivations(ctx, tx, userID, day)
+}
+
+func countFreeActivations(ctx context.Context, q queryer, userID int, day time.Time) (map[string]int, error) {
+	rows, err := q.QueryContext(ctx,
+		"SELECT boost, COUNT(*) FROM boost_activations WHERE user_id = $1 AND day = $2 AND price = 0 GROUP BY boost",
+		userID, day.Format("2006-01-02"),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	counts := map[string]int{}
+	for rows.Next() {
+		var boost string
+		var count int
+		if err := rows.Scan(&boost, &count); err != nil {
+			return nil, err
+		}
+		counts[boost] = count
+	}
+	return counts, rows.Err()
+}
+
+func (r *BoostsRepository) CreateActivation(ctx context.Context, tx *sql.Tx, userID int, boost string, day time.Time, price int64) (int, error) {
+	var id int
+	err := tx.QueryRowContext(ctx,
+		"INSERT INTO boost_activations (user_id, boost, day, price) VALUES ($1, $2, $3, $4) RETURNING id",
+		userID, boost, day.Format("2006-01-02"), price,
+	).Scan(&id)
+	if err != nil {
+		return 0, err
+	}
+	return id, nil
+}
+
+// RefillEnergy восстанавливает энергию до максимума
+func (r *BoostsRepository) RefillEnergy(ctx context.Context, tx *sql.Tx, userID int) error {
+	_, err := tx.ExecContext(ctx, "UPDATE users SET energy = max_energy, last_restoration = NOW() WHERE id = $1", userID)
+	return err
+}
+
+// StartTapBoost умножает прибыль за тап на multiplier на время duration
+func (r *BoostsRepository) StartTapBoost(ctx context.Context, tx *sql.Tx, userID int, multiplier int, duration time.Duration) error {
+	_, err := tx.ExecContext(ctx,
+		"UPDATE users SET tap_boost_multiplier = $1, tap_boost_until = NOW() + $2 * INTERVAL '1 second' WHERE id = $3",
+		multiplier, int64(duration.Seconds()), userID,
+	)
+	return err
+}
+
+// StartRegenBoost ускоряет восстановление энергии в multiplier раз на время duration
+func (r *BoostsRepository) StartRegenBoost(ctx context.Context, tx *sql.Tx, userID int, multiplier int, duration time.Duration) error {
+	_, err := tx.ExecContext(ctx,
+		`UPDATE users SET
+			regen_boost_multiplier = $1,
+			regen_boost_started_at = NOW(),
+			regen_boost_until = NOW() + $2 * INTERVAL '1 second'
+		WHERE id = $3`,
+		multiplier, int64(duration.Seconds()), userID,
+	)
+	return err
+}
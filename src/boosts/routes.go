@@ -0,0 +1,57 @@
+package boosts
+
+import (
+	"api/src/core/config"
+	"api/src/middleware"
+	"github.com/labstack/echo/v4"
+)
+
+type BoostsHandler struct {
+	service *BoostsService
+	config  *config.Config
+}
+
+func NewBoostsHandler(service *BoostsService, config *config.Config) *BoostsHandler {
+	return &BoostsHandler{service: service, config: config}
+}
+
+func RegisterRoutes(e *echo.Echo, service *BoostsService, config *config.Config) {
+	handler := NewBoostsHandler(service, config)
+
+	boostsGroup := e.Group("/boosts")
+	boostsGroup.Use(middleware.TelegramAuth(middleware.TelegramAuthConfig{
+		BotToken: handler.config.TELEGRAM_BOT_TOKEN,
+	}))
+	boostsGroup.GET("", handler.GetBoosts)
+	boostsGroup.POST("/:name/activate", handler.ActivateBoost)
+}
+
+// @Summary Список бустов
+// @Description Возвращает бусты с ценой, оставшимися бесплатными активациями и временем действия
+// @Tags boosts
+// @Accept json
+// @Produce json
+// @Success 200 {array} BoostResponse
+// @Failure 500 {object} map[string]string
+// @Router /boosts [get]
+// @Security TelegramAuth
+func (h *BoostsHandler) GetBoosts(c echo.Context) error {
+	return h.service.GetBoosts(c)
+}
+
+// @Summary Активировать буст
+// @Description Активирует буст energy_refill, multitap_x2, multitap_x5 или fast_regen. Пока есть бесплатные активации за день, буст бесплатный
+// @Tags boosts
+// @Accept json
+// @Produce json
+// @Param name path string true "Название буста"
+// @Success 200 {object} ActivateBoostResponse
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /boosts/{name}/activate [post]
+// @Security TelegramAuth
+func (h *BoostsHandler) ActivateBoost(c echo.Context) error {
+	return h.service.ActivateBoost(c)
+}
@@ -0,0 +1,199 @@
+package boosts
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"sort"
+	"time"
+
+	"api/src/core/economy"
+	"api/src/core/loger"
+	"api/src/ledger"
+	"api/src/middleware"
+	"api/src/user"
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+)
+
+var (
+	ErrUnknownBoost = errors.New("буст не найден")
+	ErrBoostActive  = errors.New("буст этого типа уже действует")
+	ErrEnergyFull   = errors.New("энергия уже полная")
+)
+
+type BoostsService struct {
+	repo        *BoostsRepository
+	userService user.UserService
+	ledger      *ledger.LedgerService
+}
+
+func NewBoostsService(repo *BoostsRepository, userService user.UserService, ledger *ledger.LedgerService) *BoostsService {
+	return &BoostsService{repo: repo, userService: userService, ledger: ledger}
+}
+
+// remaining возвращает, сколько еще действует буст с эффектом effect
+func remaining(u *BoostUserRepo, effect string, now time.Time) time.Duration {
+	var until *time.Time
+	switch effect {
+	case effectTap:
+		until = u.TapBoostUntil
+	case effectRegen:
+		until = u.RegenBoostUntil
+	}
+	if until == nil || !until.After(now) {
+		return 0
+	}
+	return until.Sub(now)
+}
+
+func buildResponse(name string, rule economy.BoostRule, freeUsed int, u *BoostUserRepo, now time.Time) BoostResponse {
+	response := BoostResponse{
+		Name:            name,
+		Price:           rule.Price,
+		FreePerDay:      rule.FreePerDay,
+		FreeLeft:        rule.FreePerDay - freeUsed,
+		DurationSeconds: int64(rule.Duration().Seconds()),
+		Multiplier:      rule.Multiplier,
+	}
+	if response.FreeLeft < 0 {
+		response.FreeLeft = 0
+	}
+	if left := remaining(u, boostEffects[name], now); left > 0 {
+		response.RemainingSeconds = int64((left + time.Second - 1) / time.Second)
+	}
+	return response
+}
+
+func (s *BoostsService) GetBoosts(c echo.Context) error {
+	ctx := c.Request().Context()
+	telegramUser := c.Get("telegram_user").(*middleware.TelegramUser)
+	rules := economy.Get()
+	now := time.Now()
+
+	u, err := s.repo.GetUser(ctx, telegramUser.ID)
+	if err != nil {
+		loger.Logger.Error("Ошибка при получении пользователя",
+			zap.Error(err),
+			zap.Int64("user_id", telegramUser.ID))
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Ошибка при получении пользователя"})
+	}
+	// Бесплатные активации обновляются в начале календарного дня, как и ежедневные награды
+	freeUsed, err := s.repo.CountFreeActivations(ctx, u.ID, now.In(rules.DailyRewardLocation()))
+	if err != nil {
+		loger.Logger.Error("Ошибка при получении бустов",
+			zap.Error(err),
+			zap.Int("user_id", u.ID))
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Ошибка при получении бустов"})
+	}
+
+	response := []BoostResponse{}
+	for name, rule := range rules.Boosts {
+		if _, ok := boostEffects[name]; !ok {
+			continue
+		}
+		response = append(response, buildResponse(name, rule, freeUsed[name], u, now))
+	}
+	sort.Slice(response, func(i, j int) bool { return response[i].Name < response[j].Name })
+	return c.JSON(http.StatusOK, response)
+}
+
+// ActivateBoost активирует буст: бесплатно, пока не исчерпан дневной лимит, иначе за монеты
+func (s *BoostsService) ActivateBoost(c echo.Context) error {
+	ctx := c.Request().Context()
+	telegramUser := c.Get("telegram_user").(*middleware.TelegramUser)
+	rules := economy.Get()
+	name := c.Param("name")
+
+	rule, ok := rules.Boosts[name]
+	effect, known := boostEffects[name]
+	if !ok || !known {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": ErrUnknownBoost.Error()})
+	}
+
+	// Начисляем доход и энергию по старым правилам, чтобы буст не действовал задним числом
+	if _, err := s.userService.AccrueUser(ctx, telegramUser.ID); err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Ошибка при начислении энергии"})
+	}
+
+	var response ActivateBoostResponse
+	err := s.repo.WithTx(ctx, func(tx *sql.Tx) error {
+		now := time.Now()
+		u, err := s.repo.LockUser(ctx, tx, telegramUser.ID)
+		if err != nil {
+			return err
+		}
+		if remaining(u, effect, now) > 0 {
+			return ErrBoostActive
+		}
+		if effect == effectEnergyRefill && u.Energy >= u.MaxEnergy {
+			return ErrEnergyFull
+		}
+
+		day := now.In(rules.DailyRewardLocation())
+		freeUsed, err := s.repo.CountFreeActivationsTx(ctx, tx, u.ID, day)
+		if err != nil {
+			return err
+		}
+		var price int64
+		if freeUsed[name] >= rule.FreePerDay {
+			price = rule.Price
+		}
+		activationID, err := s.repo.CreateActivation(ctx, tx, u.ID, name, day, price)
+		if err != nil {
+			return err
+		}
+		response.Paid = price
+		response.Balance, err = s.ledger.Debit(ctx, tx, ledger.Entry{
+			UserID:     u.ID,
+			Amount:     price,
+			Reason:     ledger.ReasonBoostPurchase,
+			SourceType: ledger.SourceBoost,
+			SourceID:   int64(activationID),
+		})
+		if err != nil {
+			return err
+		}
+
+		switch effect {
+		case effectEnergyRefill:
+			err = s.repo.RefillEnergy(ctx, tx, u.ID)
+		case effectTap:
+			err = s.repo.StartTapBoost(ctx, tx, u.ID, rule.Multiplier, rule.Duration())
+			until := now.Add(rule.Duration())
+			u.TapBoostUntil = &until
+		case effectRegen:
+			err = s.repo.StartRegenBoost(ctx, tx, u.ID, rule.Multiplier, rule.Duration())
+			until := now.Add(rule.Duration())
+			u.RegenBoostUntil = &until
+		}
+		if err != nil {
+			return err
+		}
+		if price == 0 {
+			freeUsed[name]++
+		}
+		response.Boost = buildResponse(name, rule, freeUsed[name], u, now)
+		return nil
+	})
+	switch {
+	case errors.Is(err, ErrBoostActive):
+		return c.JSON(http.StatusConflict, map[string]string{"error": err.Error()})
+	case errors.Is(err, ErrEnergyFull):
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	case errors.Is(err, ledger.ErrInsufficientFunds):
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Недостаточно средств"})
+	case err != nil:
+		loger.Logger.Error("Ошибка при активации буста",
+			zap.Error(err),
+			zap.String("boost", name),
+			zap.Int64("user_id", telegramUser.ID))
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Ошибка при активации буста"})
+	}
+
+	loger.Logger.Info("Буст активирован",
+		zap.Int64("user_id", telegramUser.ID),
+		zap.String("boost", name),
+		zap.Int64("paid", response.Paid))
+	return c.JSON(http.StatusOK, response)
+}
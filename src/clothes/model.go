@@ -1,5 +1,16 @@
 package clothes
 
+// Слоты, в которые надевается одежда. Тип одежды совпадает с ее слотом
+const (
+	SlotHead = "head"
+	SlotBody = "body"
+	SlotLegs = "legs"
+	SlotFoot = "foot"
+	SlotHand = "hand"
+)
+
+var slots = map[string]bool{SlotHead: true, SlotBody: true, SlotLegs: true, SlotFoot: true, SlotHand: true}
+
 type ClotheRepo struct {
 	ID int `json:"id"`
 	Name string `json:"name"`
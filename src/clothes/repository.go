@@ -1,6 +1,8 @@
 package clothes
 
 import (
+	"api/db"
+	"context"
 	"database/sql"
 	"strconv"
 )
@@ -73,83 +75,71 @@ func (r *ClothesRepository) ExistsClothesUser(userID int, clothesID int) (bool,
 	return exists, nil
 }
 
-func (r *ClothesRepository) AddClotheUser(userID int, clothesID string) error {
-	clothID, err := strconv.Atoi(clothesID)
-	if err != nil {
-		return err
-	}
-	_, err = r.db.Exec("INSERT INTO clothes_user (user_id, clothes_id) VALUES ($1, $2)", userID, clothID)
-	return err
+// WithTx выполняет fn в одной транзакции базы данных
+func (r *ClothesRepository) WithTx(ctx context.Context, fn func(tx *sql.Tx) error) error {
+	return db.WithTx(ctx, r.db, fn)
 }
 
-func (r *ClothesRepository) UpdateUserBalance(userID int, balance int64) error {
-	_, err := r.db.Exec("UPDATE users SET balance = $1 WHERE id = $2", balance, userID)
-	return err
+// LockUser блокирует строку пользователя до конца транзакции
+func (r *ClothesRepository) LockUser(ctx context.Context, tx *sql.Tx, userID int) error {
+	var id int
+	return tx.QueryRowContext(ctx, "SELECT id FROM users WHERE id = $1 FOR UPDATE", userID).Scan(&id)
 }
 
-func (r *ClothesRepository) EquipClothe(userID int, clothesID string) error {
-	clothID, err := strconv.Atoi(clothesID)
+func (r *ClothesRepository) ExistsClothesUserTx(ctx context.Context, tx *sql.Tx, userID int, clothesID int) (bool, error) {
+	var exists bool
+	err := tx.QueryRowContext(ctx, "SELECT EXISTS(SELECT 1 FROM clothes_user WHERE user_id = $1 AND clothes_id = $2)", userID, clothesID).Scan(&exists)
 	if err != nil {
-		return err
+		return false, err
 	}
+	return exists, nil
+}
 
-	// Сначала проверяем, есть ли у пользователя эта вещь
-	exists, err := r.ExistsClothesUser(userID, clothID)
+func (r *ClothesRepository) AddClotheUser(ctx context.Context, tx *sql.Tx, userID int, clothesID int) error {
+	_, err := tx.ExecContext(ctx, "INSERT INTO clothes_user (user_id, clothes_id) VALUES ($1, $2)", userID, clothesID)
+	return err
+}
+
+func (r *ClothesRepository) IsEquipped(userID int, clothesID int) (bool, error) {
+	var equipped bool
+	err := r.db.QueryRow("SELECT EXISTS(SELECT 1 FROM equipped_items WHERE user_id = $1 AND clothes_id = $2)", userID, clothesID).Scan(&equipped)
 	if err != nil {
-		return err
-	}
-	if !exists {
-		return sql.ErrNoRows
+		return false, err
 	}
-
-	// Обновляем экипированную вещь
-	_, err = r.db.Exec("UPDATE users SET equipped_clothes_id = $1 WHERE id = $2", clothID, userID)
-	return err
+	return equipped, nil
 }
 
-func (r *ClothesRepository) GetClotheByUrlImage(urlImage string) (*ClotheRepo, error) {
+// GetEquippedTx возвращает вещь, надетую в слот slot. Если слот пуст, возвращает sql.ErrNoRows
+func (r *ClothesRepository) GetEquippedTx(ctx context.Context, tx *sql.Tx, userID int, slot string) (*ClotheRepo, error) {
 	var cloth ClotheRepo
-	err := r.db.QueryRow("SELECT id, name, description, url_image, price, type, rarity, per_for_tap, plus_energy FROM clothes WHERE url_image = $1", urlImage).
-		Scan(&cloth.ID, &cloth.Name, &cloth.Description, &cloth.UrlImage, &cloth.Price, &cloth.Type, &cloth.Rarity, &cloth.PerForTap, &cloth.PlusEnergy)
+	err := tx.QueryRowContext(ctx,
+		`SELECT c.id, c.name, c.description, c.url_image, c.price, c.type, c.rarity, c.per_for_tap, c.plus_energy
+		FROM equipped_items e
+		JOIN clothes c ON c.id = e.clothes_id
+		WHERE e.user_id = $1 AND e.slot = $2`,
+		userID, slot,
+	).Scan(&cloth.ID, &cloth.Name, &cloth.Description, &cloth.UrlImage, &cloth.Price, &cloth.Type, &cloth.Rarity, &cloth.PerForTap, &cloth.PlusEnergy)
 	if err != nil {
 		return nil, err
 	}
 	return &cloth, nil
 }
 
-func (r *ClothesRepository) UpdateUserHead(userID int, urlImage string) error {
-	_, err := r.db.Exec("UPDATE users SET head = $1 WHERE id = $2", urlImage, userID)
+// EquipTx надевает вещь в слот slot, заменяя надетую там раньше
+func (r *ClothesRepository) EquipTx(ctx context.Context, tx *sql.Tx, userID int, slot string, clothesID int) error {
+	_, err := tx.ExecContext(ctx,
+		`INSERT INTO equipped_items (user_id, slot, clothes_id) VALUES ($1, $2, $3)
+		ON CONFLICT (user_id, slot) DO UPDATE SET clothes_id = EXCLUDED.clothes_id`,
+		userID, slot, clothesID,
+	)
 	return err
 }
 
-func (r *ClothesRepository) UpdateUserBody(userID int, urlImage string) error {
-	_, err := r.db.Exec("UPDATE users SET body = $1 WHERE id = $2", urlImage, userID)
+// AddUserStats изменяет максимальную энергию и прибыль за тап пользователя на разницу бонусов одежды
+func (r *ClothesRepository) AddUserStats(ctx context.Context, tx *sql.Tx, userID int, plusEnergy int, perForTap int) error {
+	_, err := tx.ExecContext(ctx,
+		"UPDATE users SET max_energy = max_energy + $1, profit_for_tap = profit_for_tap + $2 WHERE id = $3",
+		plusEnergy, perForTap, userID,
+	)
 	return err
 }
-
-func (r *ClothesRepository) UpdateUserLegs(userID int, urlImage string) error {
-	_, err := r.db.Exec("UPDATE users SET legs = $1 WHERE id = $2", urlImage, userID)
-	return err
-}
-
-func (r *ClothesRepository) UpdateUserFoot(userID int, urlImage string) error {
-	_, err := r.db.Exec("UPDATE users SET foot = $1 WHERE id = $2", urlImage, userID)
-	return err
-}
-
-func (r *ClothesRepository) UpdateUserHand(userID int, urlImage string) error {
-	_, err := r.db.Exec("UPDATE users SET hand = $1 WHERE id = $2", urlImage, userID)
-	return err
-}
-
-func (r *ClothesRepository) UpdateUserEnergy(userID int, energy int) error {
-	_, err := r.db.Exec("UPDATE users SET max_energy = $1 WHERE id = $2", energy, userID)
-	return err
-}
-
-func (r *ClothesRepository) UpdateUserPerForTap(userID int, perForTap int) error {
-	_, err := r.db.Exec("UPDATE users SET profit_for_tap = $1 WHERE id = $2", perForTap, userID)
-	return err
-}
-
-
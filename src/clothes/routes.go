@@ -63,6 +63,7 @@ func (h *ClothesHandler) GetClothe(c echo.Context) error {
 // @Param id path string true "ID предмета одежды"
 // @Success 200 {object} ClotheUserResponse
 // @Failure 400 {object} map[string]string
+// @Failure 409 {object} map[string]string
 // @Failure 500 {object} map[string]string
 // @Router /clothes/buy/{id} [post]
 // @Security TelegramAuth
@@ -1,22 +1,35 @@
 package clothes
 
 import (
+	"context"
+	"database/sql"
+	"errors"
 	"github.com/labstack/echo/v4"
+	"github.com/lib/pq"
+	"api/src/core/economy"
+	"api/src/core/events"
 	"api/src/core/loger"
+	"api/src/ledger"
 	"api/src/middleware"
 	"api/src/user"
 	"net/http"
-	"fmt"
 	"go.uber.org/zap"
 )
 
+var (
+	ErrAlreadyOwned      = errors.New("одежда уже куплена")
+	ErrInsufficientFunds = errors.New("недостаточно средств")
+	ErrNotOwned          = errors.New("одежда не куплена")
+)
+
 type ClothesService struct {
 	repo *ClothesRepository
 	userService user.UserService
+	ledger *ledger.LedgerService
 }
 
-func NewClothesService(repo *ClothesRepository, userService user.UserService) *ClothesService {
-	return &ClothesService{repo: repo, userService: userService}
+func NewClothesService(repo *ClothesRepository, userService user.UserService, ledger *ledger.LedgerService) *ClothesService {
+	return &ClothesService{repo: repo, userService: userService, ledger: ledger}
 }
 
 func (s *ClothesService) GetClothes(c echo.Context) error {
@@ -85,31 +98,15 @@ func (s *ClothesService) GetClothe(c echo.Context) error {
 		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Ошибка при проверке существования одежды пользователя"})
 	}
 
-	var active bool
 	var canBuy bool
 
-	if clothe.Type == "head" {
-		if user.Head != nil && *user.Head == clothe.UrlImage {
-			active = true
-		}
-	} else if clothe.Type == "body" {
-		if user.Body != nil && *user.Body == clothe.UrlImage {
-			active = true
-		}
-	} else if clothe.Type == "legs" {
-		if user.Legs != nil && *user.Legs == clothe.UrlImage {
-			active = true
-		}
-	} else if clothe.Type == "foot" {
-		if user.Foot != nil && *user.Foot == clothe.UrlImage {
-			active = true
-		}
-	} else if clothe.Type == "hand" {
-		if user.Hand != nil && *user.Hand == clothe.UrlImage {
-			active = true
-		}
-	} else {
-		active = false
+	active, err := s.repo.IsEquipped(user.ID, clothe.ID)
+	if err != nil {
+		loger.Logger.Error("Ошибка при проверке надетой одежды",
+			zap.Error(err),
+			zap.Int("user_id", int(user.ID)),
+			zap.Int("cloth_id", clothe.ID))
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Ошибка при проверке надетой одежды"})
 	}
 
 	if active {
@@ -161,25 +158,73 @@ func (s *ClothesService) BuyClothe(c echo.Context) error {
 			zap.Int64("user_id", telegramUser.ID))
 		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Ошибка при получении пользователя"})
 	}
-    if user.Balance < int64(clothe.Price) {
-        return c.JSON(http.StatusBadRequest, map[string]string{"error": "Недостаточно средств"})
-    }
-	err = s.repo.AddClotheUser(user.ID, id)
-	if err != nil {
+	ctx := c.Request().Context()
+	err = s.repo.WithTx(ctx, func(tx *sql.Tx) error {
+		return s.buyClotheTx(ctx, tx, user.ID, clothe)
+	})
+	switch err {
+	case nil:
+	case ErrAlreadyOwned:
+		return c.JSON(http.StatusConflict, map[string]string{"error": "Одежда уже куплена"})
+	case ErrInsufficientFunds:
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Недостаточно средств"})
+	default:
 		loger.Logger.Error("Ошибка при покупке одежды",
 			zap.Error(err),
 			zap.Int("user_id", int(user.ID)),
 			zap.String("cloth_id", id))
 		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Ошибка при покупке одежды"})
 	}
-	err = s.repo.UpdateUserBalance(user.ID, user.Balance - int64(clothe.Price))
+	return s.GetClothe(c)
+}
+
+// buyClotheTx проверяет владение и списывает цену под блокировкой строки пользователя,
+// поэтому параллельные покупки одной вещи не проходят дважды
+func (s *ClothesService) buyClotheTx(ctx context.Context, tx *sql.Tx, userID int, clothe *ClotheRepo) error {
+	if err := s.repo.LockUser(ctx, tx, userID); err != nil {
+		return err
+	}
+
+	exists, err := s.repo.ExistsClothesUserTx(ctx, tx, userID, clothe.ID)
 	if err != nil {
-		loger.Logger.Error("Ошибка при обновлении баланса",
-			zap.Error(err),
-			zap.Int("user_id", int(user.ID)))
-		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Ошибка при обновлении баланса"})
+		return err
 	}
-	return s.GetClothe(c)
+	if exists {
+		return ErrAlreadyOwned
+	}
+
+	_, err = s.ledger.Debit(ctx, tx, ledger.Entry{
+		UserID:     userID,
+		Amount:     int64(clothe.Price),
+		Reason:     ledger.ReasonClothesPurchase,
+		SourceType: ledger.SourceClothes,
+		SourceID:   int64(clothe.ID),
+	})
+	if err != nil {
+		if err == ledger.ErrInsufficientFunds {
+			return ErrInsufficientFunds
+		}
+		return err
+	}
+
+	if err := s.userService.AddXP(ctx, tx, userID, economy.Get().PurchaseXP(int64(clothe.Price))); err != nil {
+		return err
+	}
+
+	err = s.repo.AddClotheUser(ctx, tx, userID, clothe.ID)
+	if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" {
+		return ErrAlreadyOwned
+	}
+	if err != nil {
+		return err
+	}
+
+	return events.Publish(ctx, tx, events.Event{
+		Type:   events.ClothesBought,
+		UserID: userID,
+		Amount: 1,
+		Attrs:  map[string]string{"clothes_type": clothe.Type},
+	})
 }
 
 func (s *ClothesService) EquipClothe(c echo.Context) error {
@@ -191,7 +236,10 @@ func (s *ClothesService) EquipClothe(c echo.Context) error {
 			zap.String("cloth_id", id))
 		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Ошибка при получении одежды"})
 	}
-	
+	if !slots[clothe.Type] {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Эту одежду нельзя надеть"})
+	}
+
 	telegramUser := c.Get("telegram_user").(*middleware.TelegramUser)
 	user, err := s.userService.GetUser(c.Request().Context(), telegramUser.ID)
 	if err != nil {
@@ -201,86 +249,15 @@ func (s *ClothesService) EquipClothe(c echo.Context) error {
 		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Ошибка при получении пользователя"})
 	}
 
-	exists, err := s.repo.ExistsClothesUser(user.ID, clothe.ID)
-	if err != nil {
-		loger.Logger.Error("Ошибка при проверке существования одежды пользователя",
-			zap.Error(err),
-			zap.Int("user_id", int(user.ID)),
-			zap.Int("cloth_id", clothe.ID))
-		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Ошибка при проверке существования одежды пользователя"})
-	}
-	if !exists {
+	ctx := c.Request().Context()
+	err = s.repo.WithTx(ctx, func(tx *sql.Tx) error {
+		return s.equipClotheTx(ctx, tx, user.ID, clothe)
+	})
+	switch err {
+	case nil:
+	case ErrNotOwned:
 		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Одежда не куплена"})
-	}	
-	var old_clothe *ClotheRepo
-	if clothe.Type == "head" {
-		if user.Head != nil {
-			old_clothe, err = s.repo.GetClotheByUrlImage(*user.Head)
-			if err != nil {
-				loger.Logger.Error("Ошибка при получении старой одежды",
-					zap.Error(err),
-					zap.String("url_image", *user.Head))
-				return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Ошибка при получении старой одежды"})
-			}
-		}else{
-			old_clothe = nil
-		}
-		err = s.repo.UpdateUserHead(user.ID, clothe.UrlImage)
-	} else if clothe.Type == "body" {
-		if user.Body != nil {
-			old_clothe, err = s.repo.GetClotheByUrlImage(*user.Body)
-			if err != nil {
-				loger.Logger.Error("Ошибка при получении старой одежды",
-					zap.Error(err),
-					zap.String("url_image", *user.Body))
-				return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Ошибка при получении старой одежды"})
-			}
-		}else{
-			old_clothe = nil
-		}
-		err = s.repo.UpdateUserBody(user.ID, clothe.UrlImage)
-	} else if clothe.Type == "legs" {
-		if user.Legs != nil {
-			old_clothe, err = s.repo.GetClotheByUrlImage(*user.Legs)
-			if err != nil {
-				loger.Logger.Error("Ошибка при получении старой одежды",
-					zap.Error(err),
-					zap.String("url_image", *user.Legs))
-				return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Ошибка при получении старой одежды"})
-			}
-		}else{
-			old_clothe = nil
-		}
-		err = s.repo.UpdateUserLegs(user.ID, clothe.UrlImage)
-	} else if clothe.Type == "foot" {
-		if user.Foot != nil {
-			old_clothe, err = s.repo.GetClotheByUrlImage(*user.Foot)
-			if err != nil {
-				loger.Logger.Error("Ошибка при получении старой одежды",
-					zap.Error(err),
-					zap.String("url_image", *user.Foot))
-				return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Ошибка при получении старой одежды"})
-			}
-		}else{
-			old_clothe = nil
-		}
-		err = s.repo.UpdateUserFoot(user.ID, clothe.UrlImage)
-	} else if clothe.Type == "hand" {
-		if user.Hand != nil {
-			old_clothe, err = s.repo.GetClotheByUrlImage(*user.Hand)
-			if err != nil {
-				loger.Logger.Error("Ошибка при получении старой одежды",
-					zap.Error(err),
-					zap.String("url_image", *user.Hand))
-				return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Ошибка при получении старой одежды"})
-			}
-		}else{
-			old_clothe = nil
-		}
-		err = s.repo.UpdateUserHand(user.ID, clothe.UrlImage)
-	}
-
-	if err != nil {
+	default:
 		loger.Logger.Error("Ошибка при экипировке одежды",
 			zap.Error(err),
 			zap.Int("user_id", int(user.ID)),
@@ -288,41 +265,37 @@ func (s *ClothesService) EquipClothe(c echo.Context) error {
 		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Ошибка при экипировке одежды"})
 	}
 
-	var plas_energy int
-	var per_for_tap int
-	if old_clothe != nil {
-		plas_energy = clothe.PlusEnergy - old_clothe.PlusEnergy
-		loger.Logger.Debug("Расчет изменения энергии",
-			zap.Int("plas_energy", plas_energy),
-			zap.Int("old_energy", old_clothe.PlusEnergy),
-			zap.Int("new_energy", clothe.PlusEnergy))
-		per_for_tap = clothe.PerForTap - old_clothe.PerForTap
-		loger.Logger.Debug("Расчет изменения per_for_tap",
-			zap.Int("per_for_tap", per_for_tap),
-			zap.Int("old_per_for_tap", old_clothe.PerForTap),
-			zap.Int("new_per_for_tap", clothe.PerForTap))
-	} else {
-		plas_energy = clothe.PlusEnergy
-		per_for_tap = clothe.PerForTap
+	return s.GetClothe(c)
+}
+
+// equipClotheTx надевает вещь в ее слот вместо прежней и изменяет бонусы пользователя
+// на разницу между ними под блокировкой строки пользователя
+func (s *ClothesService) equipClotheTx(ctx context.Context, tx *sql.Tx, userID int, clothe *ClotheRepo) error {
+	if err := s.repo.LockUser(ctx, tx, userID); err != nil {
+		return err
 	}
 
-	err = s.repo.UpdateUserEnergy(user.ID, user.MaxEnergy + plas_energy)
+	exists, err := s.repo.ExistsClothesUserTx(ctx, tx, userID, clothe.ID)
 	if err != nil {
-		loger.Logger.Error("Ошибка при обновлении энергии",
-			zap.Error(err),
-			zap.Int("user_id", int(user.ID)))
-		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Ошибка при обновлении энергии"})
+		return err
 	}
-	
-	err = s.repo.UpdateUserPerForTap(user.ID, user.ProfitForTap + per_for_tap)
-	if err != nil {
-		loger.Logger.Error("Ошибка при обновлении per_for_tap",
-			zap.Error(err),
-			zap.Int("user_id", int(user.ID)))
-		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Ошибка при обновлении per_for_tap"})
+	if !exists {
+		return ErrNotOwned
 	}
-	
-	return s.GetClothe(c)
-}
 
+	plusEnergy, perForTap := clothe.PlusEnergy, clothe.PerForTap
+	old, err := s.repo.GetEquippedTx(ctx, tx, userID, clothe.Type)
+	switch err {
+	case nil:
+		plusEnergy -= old.PlusEnergy
+		perForTap -= old.PerForTap
+	case sql.ErrNoRows:
+	default:
+		return err
+	}
 
+	if err := s.repo.EquipTx(ctx, tx, userID, clothe.Type, clothe.ID); err != nil {
+		return err
+	}
+	return s.repo.AddUserStats(ctx, tx, userID, plusEnergy, perForTap)
+}
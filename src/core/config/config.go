@@ -4,6 +4,7 @@ import (
 	"os"
 	"fmt"
 	"github.com/joho/godotenv"
+	"go.uber.org/zap/zapcore"
 )
 
 type Config struct {
@@ -15,6 +16,40 @@ type Config struct {
 	TELEGRAM_BOT_TOKEN string
 	TELEGRAM_WEBHOOK_URL string
 	PORT string
+	ECONOMY_CONFIG_PATH string
+	TELEGRAM_APP_URL string
+	ADMIN_TOKEN string
+}
+
+// redacted скрывает секрет в логах, оставляя видным только то, что он задан
+func redacted(value string) string {
+	if value == "" {
+		return ""
+	}
+	return "***"
+}
+
+// MarshalLogObject выводит конфигурацию в лог без паролей и токенов
+func (c *Config) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	enc.AddString("DB_HOST", c.DB_HOST)
+	enc.AddString("DB_PORT", c.DB_PORT)
+	enc.AddString("DB_USER", c.DB_USER)
+	enc.AddString("DB_PASSWORD", redacted(c.DB_PASSWORD))
+	enc.AddString("DB_NAME", c.DB_NAME)
+	enc.AddString("TELEGRAM_BOT_TOKEN", redacted(c.TELEGRAM_BOT_TOKEN))
+	enc.AddString("TELEGRAM_WEBHOOK_URL", c.TELEGRAM_WEBHOOK_URL)
+	enc.AddString("PORT", c.PORT)
+	enc.AddString("ECONOMY_CONFIG_PATH", c.ECONOMY_CONFIG_PATH)
+	enc.AddString("TELEGRAM_APP_URL", c.TELEGRAM_APP_URL)
+	enc.AddString("ADMIN_TOKEN", redacted(c.ADMIN_TOKEN))
+	return nil
+}
+
+// String не дает секретам попасть в вывод fmt
+func (c *Config) String() string {
+	return fmt.Sprintf("{DB_HOST:%s DB_PORT:%s DB_USER:%s DB_PASSWORD:%s DB_NAME:%s TELEGRAM_BOT_TOKEN:%s TELEGRAM_WEBHOOK_URL:%s PORT:%s ECONOMY_CONFIG_PATH:%s TELEGRAM_APP_URL:%s ADMIN_TOKEN:%s}",
+		c.DB_HOST, c.DB_PORT, c.DB_USER, redacted(c.DB_PASSWORD), c.DB_NAME, redacted(c.TELEGRAM_BOT_TOKEN),
+		c.TELEGRAM_WEBHOOK_URL, c.PORT, c.ECONOMY_CONFIG_PATH, c.TELEGRAM_APP_URL, redacted(c.ADMIN_TOKEN))
 }
 
 func LoadConfig() (*Config, error) {
@@ -24,6 +59,9 @@ func LoadConfig() (*Config, error) {
 	}
 
 	config := GetConfig()
+	if config.ECONOMY_CONFIG_PATH == "" {
+		config.ECONOMY_CONFIG_PATH = "economy.json"
+	}
 	if err := validateConfig(config); err != nil {
 		return nil, err
 	}
@@ -40,6 +78,9 @@ func GetConfig() *Config {
 		DB_NAME: os.Getenv("DB_NAME"),
 		TELEGRAM_BOT_TOKEN: os.Getenv("TELEGRAM_BOT_TOKEN"),
 		PORT: os.Getenv("PORT"),
+		ECONOMY_CONFIG_PATH: os.Getenv("ECONOMY_CONFIG_PATH"),
+		TELEGRAM_APP_URL: os.Getenv("TELEGRAM_APP_URL"),
+		ADMIN_TOKEN: os.Getenv("ADMIN_TOKEN"),
 	}
 }
 
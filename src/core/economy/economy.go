@@ -0,0 +1,338 @@
+package economy
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync/atomic"
+	"time"
+	_ "time/tzdata"
+
+	"api/src/core/loger"
+	"go.uber.org/zap"
+)
+
+// Rules описывает параметры игровой экономики
+type Rules struct {
+	// EnergyRegenIntervalSeconds — раз в сколько секунд восстанавливается энергия
+	EnergyRegenIntervalSeconds int `json:"energy_regen_interval_seconds"`
+	// EnergyRegenAmount — сколько энергии восстанавливается за интервал
+	EnergyRegenAmount int `json:"energy_regen_amount"`
+	// OfflineIncomeCapHours — за сколько часов максимум начисляется пассивный доход, 0 — без ограничения
+	OfflineIncomeCapHours int `json:"offline_income_cap_hours"`
+	// TapMinProfit — минимальная прибыль за тап
+	TapMinProfit int `json:"tap_min_profit"`
+	// XPPerTap — опыт за один тап
+	XPPerTap int `json:"xp_per_tap"`
+	// CoinsPerPurchaseXP — за сколько потраченных на покупки монет дается единица опыта
+	CoinsPerPurchaseXP int `json:"coins_per_purchase_xp"`
+	// ReferralInviterBonus — бонус пригласившему за нового друга
+	ReferralInviterBonus int64 `json:"referral_inviter_bonus"`
+	// ReferralInviteeBonus — бонус новому пользователю, пришедшему по приглашению
+	ReferralInviteeBonus int64 `json:"referral_invitee_bonus"`
+	// ReferralIncomeSharePercent — процент пассивного дохода друга, который получает пригласивший
+	ReferralIncomeSharePercent int `json:"referral_income_share_percent"`
+	// DailyRewards — награды за дни серии ежедневных входов, после последнего дня награда не растет
+	DailyRewards []int64 `json:"daily_rewards"`
+	// DailyRewardTimezone — часовой пояс, в котором отсчитываются календарные дни
+	DailyRewardTimezone string `json:"daily_reward_timezone"`
+	// Boosts — параметры временных бустов по их названию
+	Boosts map[string]BoostRule `json:"boosts"`
+	// BattleCooldownMinutes — как часто пользователь может нападать
+	BattleCooldownMinutes int `json:"battle_cooldown_minutes"`
+	// BattleShieldMinutes — сколько после нападения на пользователя его нельзя атаковать снова
+	BattleShieldMinutes int `json:"battle_shield_minutes"`
+	// BattleMatchRangePercent — допустимое отличие защиты соперника от атаки нападающего в процентах
+	BattleMatchRangePercent int `json:"battle_match_range_percent"`
+	// BattleStealPercent — какую долю ненакопленного пассивного дохода проигравшего забирает победитель
+	BattleStealPercent int `json:"battle_steal_percent"`
+	// BattleMaxSteal — максимальная добыча за один бой
+	BattleMaxSteal int64 `json:"battle_max_steal"`
+	// DailyComboReward — награда за улучшение трех работников комбо дня
+	DailyComboReward int64 `json:"daily_combo_reward"`
+	// UpgradeSkipSecondsPerGem — сколько секунд улучшения работника пропускает один кристалл
+	UpgradeSkipSecondsPerGem int `json:"upgrade_skip_seconds_per_gem"`
+
+	dailyRewardLocation *time.Location
+}
+
+// BoostRule описывает один буст
+type BoostRule struct {
+	// Price — стоимость активации после того, как бесплатные активации за день закончились
+	Price int64 `json:"price"`
+	// FreePerDay — сколько раз в календарный день буст активируется бесплатно
+	FreePerDay int `json:"free_per_day"`
+	// DurationMinutes — длительность действия, 0 — буст срабатывает мгновенно
+	DurationMinutes int `json:"duration_minutes"`
+	// Multiplier — множитель прибыли за тап или скорости восстановления энергии
+	Multiplier int `json:"multiplier"`
+}
+
+// boostFileRule — буст в файле настроек. Указатели отличают незаданное поле от нуля
+type boostFileRule struct {
+	Price           *int64 `json:"price"`
+	FreePerDay      *int   `json:"free_per_day"`
+	DurationMinutes *int   `json:"duration_minutes"`
+	Multiplier      *int   `json:"multiplier"`
+}
+
+// parseBoosts проверяет, что у каждого буста заданы цена и число бесплатных активаций,
+// а длительность и множитель заданы вместе или не заданы оба
+func parseBoosts(file map[string]boostFileRule) (map[string]BoostRule, error) {
+	boosts := make(map[string]BoostRule, len(file))
+	for name, b := range file {
+		if b.Price == nil || b.FreePerDay == nil {
+			return nil, fmt.Errorf("у буста %s должны быть заданы price и free_per_day", name)
+		}
+		if (b.DurationMinutes == nil) != (b.Multiplier == nil) {
+			return nil, fmt.Errorf("у буста %s duration_minutes и multiplier задаются вместе", name)
+		}
+		rule := BoostRule{Price: *b.Price, FreePerDay: *b.FreePerDay}
+		if b.DurationMinutes != nil {
+			if *b.DurationMinutes <= 0 {
+				return nil, fmt.Errorf("duration_minutes буста %s должен быть положительным", name)
+			}
+			rule.DurationMinutes = *b.DurationMinutes
+			rule.Multiplier = *b.Multiplier
+		}
+		boosts[name] = rule
+	}
+	return boosts, nil
+}
+
+// Duration возвращает длительность действия буста
+func (b BoostRule) Duration() time.Duration {
+	return time.Duration(b.DurationMinutes) * time.Minute
+}
+
+// DailyReward возвращает награду за день серии streak, начиная с 1
+func (r *Rules) DailyReward(streak int) int64 {
+	if streak < 1 {
+		streak = 1
+	}
+	if streak > len(r.DailyRewards) {
+		streak = len(r.DailyRewards)
+	}
+	return r.DailyRewards[streak-1]
+}
+
+// DailyRewardLocation возвращает часовой пояс ежедневных наград
+func (r *Rules) DailyRewardLocation() *time.Location {
+	return r.dailyRewardLocation
+}
+
+// PurchaseXP возвращает опыт за покупку стоимостью cost
+func (r *Rules) PurchaseXP(cost int64) int64 {
+	return cost / int64(r.CoinsPerPurchaseXP)
+}
+
+// EnergyRegenInterval возвращает интервал восстановления энергии
+func (r *Rules) EnergyRegenInterval() time.Duration {
+	return time.Duration(r.EnergyRegenIntervalSeconds) * time.Second
+}
+
+// OfflineIncomeCap возвращает максимальное время начисления пассивного дохода, 0 — без ограничения
+func (r *Rules) OfflineIncomeCap() time.Duration {
+	return time.Duration(r.OfflineIncomeCapHours) * time.Hour
+}
+
+// BattleCooldown возвращает время между нападениями одного пользователя
+func (r *Rules) BattleCooldown() time.Duration {
+	return time.Duration(r.BattleCooldownMinutes) * time.Minute
+}
+
+// BattleShield возвращает время защиты пользователя после нападения на него
+func (r *Rules) BattleShield() time.Duration {
+	return time.Duration(r.BattleShieldMinutes) * time.Minute
+}
+
+// UpgradeSkipGems возвращает, сколько кристаллов стоит досрочно завершить улучшение за remaining
+func (r *Rules) UpgradeSkipGems(remaining time.Duration) int64 {
+	if remaining <= 0 {
+		return 0
+	}
+	perGem := time.Duration(r.UpgradeSkipSecondsPerGem) * time.Second
+	return int64((remaining + perGem - 1) / perGem)
+}
+
+// DefaultRules — значения, которые действуют, если файл настроек не найден
+func DefaultRules() *Rules {
+	return &Rules{
+		EnergyRegenIntervalSeconds: 120,
+		EnergyRegenAmount:          1,
+		OfflineIncomeCapHours:      3,
+		TapMinProfit:               1,
+		XPPerTap:                   1,
+		CoinsPerPurchaseXP:         100,
+		ReferralInviterBonus:       5000,
+		ReferralInviteeBonus:       5000,
+		ReferralIncomeSharePercent: 10,
+		DailyRewards:               []int64{500, 1000, 2500, 5000, 15000, 25000, 100000},
+		DailyRewardTimezone:        "Europe/Moscow",
+		Boosts: map[string]BoostRule{
+			"energy_refill": {Price: 2000, FreePerDay: 6},
+			"multitap_x2":   {Price: 5000, FreePerDay: 1, DurationMinutes: 10, Multiplier: 2},
+			"multitap_x5":   {Price: 20000, DurationMinutes: 5, Multiplier: 5},
+			"fast_regen":    {Price: 10000, FreePerDay: 1, DurationMinutes: 30, Multiplier: 3},
+		},
+		BattleCooldownMinutes:    30,
+		BattleShieldMinutes:      60,
+		BattleMatchRangePercent:  20,
+		BattleStealPercent:       10,
+		BattleMaxSteal:           100000,
+		DailyComboReward:         1000000,
+		UpgradeSkipSecondsPerGem: 60,
+		dailyRewardLocation:      mustLoadLocation("Europe/Moscow"),
+	}
+}
+
+func mustLoadLocation(name string) *time.Location {
+	location, err := time.LoadLocation(name)
+	if err != nil {
+		panic(err)
+	}
+	return location
+}
+
+func (r *Rules) validate() error {
+	if r.EnergyRegenIntervalSeconds <= 0 {
+		return fmt.Errorf("energy_regen_interval_seconds должен быть положительным")
+	}
+	if r.EnergyRegenAmount <= 0 {
+		return fmt.Errorf("energy_regen_amount должен быть положительным")
+	}
+	if r.OfflineIncomeCapHours < 0 {
+		return fmt.Errorf("offline_income_cap_hours не может быть отрицательным")
+	}
+	if r.TapMinProfit <= 0 {
+		return fmt.Errorf("tap_min_profit должен быть положительным")
+	}
+	if r.XPPerTap < 0 {
+		return fmt.Errorf("xp_per_tap не может быть отрицательным")
+	}
+	if r.CoinsPerPurchaseXP <= 0 {
+		return fmt.Errorf("coins_per_purchase_xp должен быть положительным")
+	}
+	if r.ReferralInviterBonus < 0 || r.ReferralInviteeBonus < 0 {
+		return fmt.Errorf("реферальные бонусы не могут быть отрицательными")
+	}
+	if r.ReferralIncomeSharePercent < 0 || r.ReferralIncomeSharePercent > 100 {
+		return fmt.Errorf("referral_income_share_percent должен быть от 0 до 100")
+	}
+	if len(r.DailyRewards) == 0 {
+		return fmt.Errorf("daily_rewards не может быть пустым")
+	}
+	for _, reward := range r.DailyRewards {
+		if reward < 0 {
+			return fmt.Errorf("daily_rewards не могут быть отрицательными")
+		}
+	}
+	for name, boost := range r.Boosts {
+		if boost.Price < 0 || boost.FreePerDay < 0 || boost.DurationMinutes < 0 || boost.Multiplier < 0 {
+			return fmt.Errorf("параметры буста %s не могут быть отрицательными", name)
+		}
+		if boost.DurationMinutes > 0 && boost.Multiplier < 1 {
+			return fmt.Errorf("множитель буста %s должен быть не меньше 1", name)
+		}
+	}
+	if r.BattleCooldownMinutes < 0 || r.BattleShieldMinutes < 0 || r.BattleMatchRangePercent < 0 {
+		return fmt.Errorf("battle_cooldown_minutes, battle_shield_minutes и battle_match_range_percent не могут быть отрицательными")
+	}
+	if r.BattleStealPercent < 0 || r.BattleStealPercent > 100 {
+		return fmt.Errorf("battle_steal_percent должен быть от 0 до 100")
+	}
+	if r.BattleMaxSteal < 0 {
+		return fmt.Errorf("battle_max_steal не может быть отрицательным")
+	}
+	if r.DailyComboReward < 0 {
+		return fmt.Errorf("daily_combo_reward не может быть отрицательным")
+	}
+	if r.UpgradeSkipSecondsPerGem <= 0 {
+		return fmt.Errorf("upgrade_skip_seconds_per_gem должен быть положительным")
+	}
+	location, err := time.LoadLocation(r.DailyRewardTimezone)
+	if err != nil {
+		return fmt.Errorf("неверный daily_reward_timezone: %v", err)
+	}
+	r.dailyRewardLocation = location
+	return nil
+}
+
+var current atomic.Pointer[Rules]
+
+func init() {
+	current.Store(DefaultRules())
+}
+
+// Get возвращает действующие правила. Возвращенное значение нельзя изменять
+func Get() *Rules {
+	return current.Load()
+}
+
+// Load читает правила из JSON-файла. Незаданные поля берутся из DefaultRules
+func Load(path string) (*Rules, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	rules := DefaultRules()
+	if err := json.Unmarshal(data, rules); err != nil {
+		return nil, fmt.Errorf("ошибка разбора %s: %v", path, err)
+	}
+	// json.Unmarshal дописывает ключи в карту бустов по умолчанию и обнуляет незаданные поля,
+	// поэтому бусты из файла разбираются отдельно и заменяют карту целиком
+	var file struct {
+		Boosts map[string]boostFileRule `json:"boosts"`
+	}
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("ошибка разбора %s: %v", path, err)
+	}
+	if file.Boosts != nil {
+		boosts, err := parseBoosts(file.Boosts)
+		if err != nil {
+			return nil, fmt.Errorf("неверные настройки экономики в %s: %v", path, err)
+		}
+		rules.Boosts = boosts
+	}
+	if err := rules.validate(); err != nil {
+		return nil, fmt.Errorf("неверные настройки экономики в %s: %v", path, err)
+	}
+	return rules, nil
+}
+
+// Init загружает правила из файла и следит за его изменениями.
+// Если файла нет, действуют правила по умолчанию
+func Init(path string, interval time.Duration) {
+	var modTime time.Time
+	reload := func() {
+		info, err := os.Stat(path)
+		if err != nil {
+			if !os.IsNotExist(err) {
+				loger.Logger.Error("Ошибка при чтении настроек экономики", zap.Error(err))
+			}
+			return
+		}
+		if !info.ModTime().After(modTime) {
+			return
+		}
+		modTime = info.ModTime()
+
+		rules, err := Load(path)
+		if err != nil {
+			// Оставляем предыдущие правила, чтобы опечатка в файле не сломала игру
+			loger.Logger.Error("Ошибка при загрузке настроек экономики", zap.Error(err))
+			return
+		}
+		current.Store(rules)
+		loger.Logger.Info("Настройки экономики загружены",
+			zap.String("path", path),
+			zap.Any("rules", rules))
+	}
+
+	reload()
+	go func() {
+		for range time.Tick(interval) {
+			reload()
+		}
+	}()
+}
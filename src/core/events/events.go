@@ -0,0 +1,66 @@
+package events
+
+import (
+	"context"
+	"database/sql"
+	"sync"
+)
+
+// Типы игровых событий
+const (
+	// TapsMade — пользователь сделал Amount тапов
+	TapsMade = "taps_made"
+	// LevelReached — пользователь достиг уровня Amount
+	LevelReached = "level_reached"
+	// WorkerBought — пользователь купил или улучшил работника до уровня Amount,
+	// Attrs["worker_id"] — id работника, Attrs["worker_type"] — тип работника
+	WorkerBought = "worker_bought"
+	// UpgradePurchased — пользователь оплатил улучшение работника до уровня Amount, Attrs["worker_id"] — id работника.
+	// В отличие от WorkerBought публикуется в момент оплаты, даже если улучшение завершится позже
+	UpgradePurchased = "upgrade_purchased"
+	// ClothesBought — пользователь купил одежду, Attrs["clothes_type"] — тип одежды
+	ClothesBought = "clothes_bought"
+	// FriendInvited — по приглашению пользователя пришел новый друг
+	FriendInvited = "friend_invited"
+	// AccrualStarted — перед начислением пассивного дохода. Обработчики могут применить
+	// отложенные изменения дохода, начислив доход до момента изменения по старой ставке
+	AccrualStarted = "accrual_started"
+)
+
+// Event описывает игровое событие пользователя
+type Event struct {
+	Type   string
+	UserID int
+	Amount int64
+	Attrs  map[string]string
+}
+
+// Handler обрабатывает событие в транзакции, в которой оно произошло.
+// Ошибка обработчика откатывает всю транзакцию
+type Handler func(ctx context.Context, tx *sql.Tx, event Event) error
+
+var (
+	mu       sync.RWMutex
+	handlers = map[string][]Handler{}
+)
+
+// Subscribe регистрирует обработчик событий типа eventType
+func Subscribe(eventType string, handler Handler) {
+	mu.Lock()
+	defer mu.Unlock()
+	handlers[eventType] = append(handlers[eventType], handler)
+}
+
+// Publish синхронно передает событие всем подписчикам его типа
+func Publish(ctx context.Context, tx *sql.Tx, event Event) error {
+	mu.RLock()
+	subscribers := handlers[event.Type]
+	mu.RUnlock()
+
+	for _, handler := range subscribers {
+		if err := handler(ctx, tx, event); err != nil {
+			return err
+		}
+	}
+	return nil
+}
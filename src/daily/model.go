@@ -0,0 +1,60 @@
+package daily
+
+import "time"
+
+type ClaimRepo struct {
+	ID     int       `json:"id"`
+	UserID int       `json:"user_id"`
+	Day    time.Time `json:"day"`
+	Streak int       `json:"streak"`
+	Reward int64     `json:"reward"`
+}
+
+// CalendarDay описывает один день в календаре ежедневных наград
+type CalendarDay struct {
+	Day     int   `json:"day"`
+	Reward  int64 `json:"reward"`
+	Claimed bool  `json:"claimed"`
+	// Current — награда, которую можно забрать сегодня или которая забрана сегодня
+	Current bool `json:"current"`
+}
+
+type DailyRewardResponse struct {
+	// Streak — количество дней подряд, за которые забрана награда
+	Streak       int  `json:"streak"`
+	ClaimedToday bool `json:"claimed_today"`
+	// Claimed — награда, начисленная этим запросом. 0, если она уже была забрана сегодня
+	Claimed int64 `json:"claimed"`
+	// NextReward — награда за следующий день серии
+	NextReward int64 `json:"next_reward"`
+	// NextClaimIn — секунд до начала следующего календарного дня
+	NextClaimIn int64         `json:"next_claim_in"`
+	Calendar    []CalendarDay `json:"calendar"`
+}
+
+// ComboWorkerRepo описывает работника комбо дня и то, улучшил ли его пользователь сегодня
+type ComboWorkerRepo struct {
+	ID       int
+	Name     string
+	UrlImage string
+	Found    bool
+}
+
+// ComboSlot — одна карточка комбо дня. Работник виден, только если пользователь его уже улучшил
+type ComboSlot struct {
+	Found    bool    `json:"found"`
+	WorkerID *int    `json:"worker_id"`
+	Name     *string `json:"name"`
+	UrlImage *string `json:"url_image"`
+}
+
+type DailyComboResponse struct {
+	Day     string      `json:"day"`
+	Reward  int64       `json:"reward"`
+	Slots   []ComboSlot `json:"slots"`
+	Claimed bool        `json:"claimed"`
+	// CanClaim — все работники комбо улучшены, а награда еще не забрана
+	CanClaim bool `json:"can_claim"`
+	// NextComboIn — секунд до выбора нового комбо
+	NextComboIn int64 `json:"next_combo_in"`
+}
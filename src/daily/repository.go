@@ -0,0 +1,192 @@
+package daily
+
+import (
+	"api/db"
+	"context"
+	"database/sql"
+)
+
+type DailyRepository struct {
+	db *sql.DB
+}
+
+func NewDailyRepository(db *sql.DB) *DailyRepository {
+	return &DailyRepository{db: db}
+}
+
+// WithTx выполняет fn в одной транзакции базы данных
+func (r *DailyRepository) WithTx(ctx context.Context, fn func(tx *sql.Tx) error) error {
+	return db.WithTx(ctx, r.db, fn)
+}
+
+// LockUser блокирует строку пользователя до конца транзакции и возвращает его id
+func (r *DailyRepository) LockUser(ctx context.Context, tx *sql.Tx, tg_id int64) (int, error) {
+	var id int
+	err := tx.QueryRowContext(ctx, "SELECT id FROM users WHERE tg_id = $1 FOR UPDATE", tg_id).Scan(&id)
+	if err != nil {
+		return 0, err
+	}
+	return id, nil
+}
+
+func (r *DailyRepository) GetUserID(ctx context.Context, tg_id int64) (int, error) {
+	var id int
+	err := r.db.QueryRowContext(ctx, "SELECT id FROM users WHERE tg_id = $1", tg_id).Scan(&id)
+	if err != nil {
+		return 0, err
+	}
+	return id, nil
+}
+
+// GetLastClaim возвращает последнюю забранную награду пользователя
+func (r *DailyRepository) GetLastClaim(ctx context.Context, userID int) (*ClaimRepo, error) {
+	var claim ClaimRepo
+	err := r.db.QueryRowContext(ctx,
+		"SELECT id, user_id, day, streak, reward FROM daily_reward_claims WHERE user_id = $1 ORDER BY day DESC LIMIT 1",
+		userID,
+	).Scan(&claim.ID, &claim.UserID, &claim.Day, &claim.Streak, &claim.Reward)
+	if err != nil {
+		return nil, err
+	}
+	return &claim, nil
+}
+
+func (r *DailyRepository) GetLastClaimTx(ctx context.Context, tx *sql.Tx, userID int) (*ClaimRepo, error) {
+	var claim ClaimRepo
+	err := tx.QueryRowContext(ctx,
+		"SELECT id, user_id, day, streak, reward FROM daily_reward_claims WHERE user_id = $1 ORDER BY day DESC LIMIT 1",
+		userID,
+	).Scan(&claim.ID, &claim.UserID, &claim.Day, &claim.Streak, &claim.Reward)
+	if err != nil {
+		return nil, err
+	}
+	return &claim, nil
+}
+
+func (r *DailyRepository) CreateClaim(ctx context.Context, tx *sql.Tx, claim ClaimRepo) (int, error) {
+	var id int
+	err := tx.QueryRowContext(ctx,
+		"INSERT INTO daily_reward_claims (user_id, day, streak, reward) VALUES ($1, $2, $3, $4) RETURNING id",
+		claim.UserID, claim.Day.Format("2006-01-02"), claim.Streak, claim.Reward,
+	).Scan(&id)
+	if err != nil {
+		return 0, err
+	}
+	return id, nil
+}
+
+type queryer interface {
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+}
+
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+}
+
+// comboCandidates — работники, которых может улучшить любой пользователь: с уровнями улучшения,
+// без условий открытия и из категорий основного списка (sort_order > 0)
+const comboCandidates = `SELECT w.id FROM workers w
+	JOIN worker_categories c ON c.slug = w.type AND c.sort_order > 0
+	WHERE EXISTS (SELECT 1 FROM workers_upgrade u WHERE u.id_worker = w.id)
+		AND NOT EXISTS (SELECT 1 FROM worker_prerequisites p WHERE p.worker_id = w.id)`
+
+// EnsureCombo выбирает комбо дня day из comboSize случайных работников, если оно еще не выбрано.
+// Пока подходящих работников меньше comboSize, комбо не создается
+func (r *DailyRepository) EnsureCombo(ctx context.Context, day string, reward int64) error {
+	return ensureCombo(ctx, r.db, day, reward)
+}
+
+func (r *DailyRepository) EnsureComboTx(ctx context.Context, tx *sql.Tx, day string, reward int64) error {
+	return ensureCombo(ctx, tx, day, reward)
+}
+
+func ensureCombo(ctx context.Context, q execer, day string, reward int64) error {
+	_, err := q.ExecContext(ctx,
+		`WITH candidates AS (`+comboCandidates+`)
+		INSERT INTO daily_combos (day, worker_ids, reward)
+		SELECT $1, ARRAY(SELECT id FROM candidates ORDER BY random() LIMIT $2), $3
+		WHERE (SELECT COUNT(*) FROM candidates) >= $2
+		ON CONFLICT (day) DO NOTHING`,
+		day, comboSize, reward,
+	)
+	return err
+}
+
+// GetCombo возвращает награду и работников комбо дня day с отметками, кого пользователь уже улучшил.
+// Если комбо не выбрано, возвращает sql.ErrNoRows
+func (r *DailyRepository) GetCombo(ctx context.Context, userID int, day string) (int64, []ComboWorkerRepo, error) {
+	return getCombo(ctx, r.db, userID, day)
+}
+
+func (r *DailyRepository) GetComboTx(ctx context.Context, tx *sql.Tx, userID int, day string) (int64, []ComboWorkerRepo, error) {
+	return getCombo(ctx, tx, userID, day)
+}
+
+func getCombo(ctx context.Context, q queryer, userID int, day string) (int64, []ComboWorkerRepo, error) {
+	rows, err := q.QueryContext(ctx,
+		`SELECT c.reward, w.id, w.name, w.url_image, p.worker_id IS NOT NULL
+		FROM daily_combos c
+		CROSS JOIN LATERAL unnest(c.worker_ids) WITH ORDINALITY AS cw(worker_id, position)
+		JOIN workers w ON w.id = cw.worker_id
+		LEFT JOIN daily_combo_progress p ON p.user_id = $1 AND p.day = c.day AND p.worker_id = cw.worker_id
+		WHERE c.day = $2
+		ORDER BY cw.position`,
+		userID, day,
+	)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer rows.Close()
+	var reward int64
+	workers := []ComboWorkerRepo{}
+	for rows.Next() {
+		var worker ComboWorkerRepo
+		if err := rows.Scan(&reward, &worker.ID, &worker.Name, &worker.UrlImage, &worker.Found); err != nil {
+			return 0, nil, err
+		}
+		workers = append(workers, worker)
+	}
+	if err := rows.Err(); err != nil {
+		return 0, nil, err
+	}
+	if len(workers) == 0 {
+		return 0, nil, sql.ErrNoRows
+	}
+	return reward, workers, nil
+}
+
+// AddComboProgress отмечает работника улучшенным сегодня, если он входит в комбо дня day
+func (r *DailyRepository) AddComboProgress(ctx context.Context, tx *sql.Tx, userID int, day string, workerID int) error {
+	_, err := tx.ExecContext(ctx,
+		`INSERT INTO daily_combo_progress (user_id, day, worker_id)
+		SELECT $1, day, $3 FROM daily_combos WHERE day = $2 AND $3 = ANY(worker_ids)
+		ON CONFLICT DO NOTHING`,
+		userID, day, workerID,
+	)
+	return err
+}
+
+func (r *DailyRepository) IsComboClaimed(ctx context.Context, userID int, day string) (bool, error) {
+	var claimed bool
+	err := r.db.QueryRowContext(ctx,
+		"SELECT EXISTS (SELECT 1 FROM daily_combo_claims WHERE user_id = $1 AND day = $2)",
+		userID, day,
+	).Scan(&claimed)
+	if err != nil {
+		return false, err
+	}
+	return claimed, nil
+}
+
+// CreateComboClaim сохраняет получение награды за комбо дня. Если награда уже забрана, возвращает sql.ErrNoRows
+func (r *DailyRepository) CreateComboClaim(ctx context.Context, tx *sql.Tx, userID int, day string, reward int64) (int, error) {
+	var id int
+	err := tx.QueryRowContext(ctx,
+		"INSERT INTO daily_combo_claims (user_id, day, reward) VALUES ($1, $2, $3) ON CONFLICT (user_id, day) DO NOTHING RETURNING id",
+		userID, day, reward,
+	).Scan(&id)
+	if err != nil {
+		return 0, err
+	}
+	return id, nil
+}
@@ -0,0 +1,85 @@
+package daily
+
+import (
+	"api/src/core/config"
+	"api/src/middleware"
+	"github.com/labstack/echo/v4"
+)
+
+type DailyHandler struct {
+	service *DailyService
+	config  *config.Config
+}
+
+func NewDailyHandler(service *DailyService, config *config.Config) *DailyHandler {
+	return &DailyHandler{service: service, config: config}
+}
+
+func RegisterRoutes(e *echo.Echo, service *DailyService, config *config.Config) {
+	handler := NewDailyHandler(service, config)
+
+	dailyGroup := e.Group("/daily")
+	dailyGroup.Use(middleware.TelegramAuth(middleware.TelegramAuthConfig{
+		BotToken: handler.config.TELEGRAM_BOT_TOKEN,
+	}))
+	dailyGroup.GET("", handler.GetDaily)
+	dailyGroup.POST("/claim", handler.ClaimDaily)
+	dailyGroup.GET("/combo", handler.GetDailyCombo)
+	dailyGroup.POST("/combo/claim", handler.ClaimDailyCombo)
+}
+
+// @Summary Календарь ежедневных наград
+// @Description Возвращает текущую серию ежедневных входов и календарь наград
+// @Tags daily
+// @Accept json
+// @Produce json
+// @Success 200 {object} DailyRewardResponse
+// @Failure 500 {object} map[string]string
+// @Router /daily [get]
+// @Security TelegramAuth
+func (h *DailyHandler) GetDaily(c echo.Context) error {
+	return h.service.GetDaily(c)
+}
+
+// @Summary Забрать ежедневную награду
+// @Description Начисляет награду за сегодняшний день серии. Повторный запрос в тот же день ничего не начисляет
+// @Tags daily
+// @Accept json
+// @Produce json
+// @Success 200 {object} DailyRewardResponse
+// @Failure 500 {object} map[string]string
+// @Router /daily/claim [post]
+// @Security TelegramAuth
+func (h *DailyHandler) ClaimDaily(c echo.Context) error {
+	return h.service.ClaimDaily(c)
+}
+
+// @Summary Комбо дня
+// @Description Возвращает комбо дня: три работника, которых нужно улучшить сегодня. Работник показывается только после того, как пользователь его улучшил
+// @Tags daily
+// @Accept json
+// @Produce json
+// @Success 200 {object} DailyComboResponse
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /daily/combo [get]
+// @Security TelegramAuth
+func (h *DailyHandler) GetDailyCombo(c echo.Context) error {
+	return h.service.GetDailyCombo(c)
+}
+
+// @Summary Забрать награду за комбо дня
+// @Description Начисляет награду, если сегодня улучшены все работники комбо дня
+// @Tags daily
+// @Accept json
+// @Produce json
+// @Success 200 {object} DailyComboResponse
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /daily/combo/claim [post]
+// @Security TelegramAuth
+func (h *DailyHandler) ClaimDailyCombo(c echo.Context) error {
+	return h.service.ClaimDailyCombo(c)
+}
@@ -0,0 +1,357 @@
+package daily
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"api/src/core/economy"
+	"api/src/core/events"
+	"api/src/core/loger"
+	"api/src/ledger"
+	"api/src/middleware"
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+)
+
+const dayLayout = "2006-01-02"
+
+// comboSize — сколько работников входит в комбо дня
+const comboSize = 3
+
+var (
+	ErrComboNotFound     = errors.New("комбо дня еще не выбрано")
+	ErrComboNotCollected = errors.New("улучшены не все работники комбо дня")
+	ErrComboClaimed      = errors.New("награда за комбо дня уже получена")
+)
+
+type DailyService struct {
+	repo   *DailyRepository
+	ledger *ledger.LedgerService
+}
+
+func NewDailyService(repo *DailyRepository, ledger *ledger.LedgerService) *DailyService {
+	return &DailyService{repo: repo, ledger: ledger}
+}
+
+// currentStreak возвращает действующую серию на момент now. Серия сбрасывается,
+// если последняя награда забрана раньше вчерашнего календарного дня
+func currentStreak(last *ClaimRepo, now time.Time, location *time.Location) (streak int, claimedToday bool) {
+	if last == nil {
+		return 0, false
+	}
+	local := now.In(location)
+	today := local.Format(dayLayout)
+	yesterday := time.Date(local.Year(), local.Month(), local.Day()-1, 0, 0, 0, 0, location).Format(dayLayout)
+
+	switch last.Day.Format(dayLayout) {
+	case today:
+		return last.Streak, true
+	case yesterday:
+		return last.Streak, false
+	default:
+		return 0, false
+	}
+}
+
+// nextDay возвращает начало следующего календарного дня
+func nextDay(now time.Time, location *time.Location) time.Time {
+	local := now.In(location)
+	return time.Date(local.Year(), local.Month(), local.Day()+1, 0, 0, 0, 0, location)
+}
+
+// buildResponse собирает календарь серии для клиента
+func buildResponse(streak int, claimedToday bool, now time.Time, rules *economy.Rules) *DailyRewardResponse {
+	location := rules.DailyRewardLocation()
+	tomorrow := nextDay(now, location)
+
+	days := len(rules.DailyRewards)
+	claimedDays := streak
+	if claimedDays > days {
+		claimedDays = days
+	}
+	current := claimedDays
+	if !claimedToday && current < days {
+		current++
+	}
+
+	response := &DailyRewardResponse{
+		Streak:       streak,
+		ClaimedToday: claimedToday,
+		NextReward:   rules.DailyReward(streak + 1),
+		NextClaimIn:  int64(tomorrow.Sub(now).Seconds()),
+		Calendar:     make([]CalendarDay, 0, days),
+	}
+	for day := 1; day <= days; day++ {
+		response.Calendar = append(response.Calendar, CalendarDay{
+			Day:     day,
+			Reward:  rules.DailyRewards[day-1],
+			Claimed: day <= claimedDays,
+			Current: day == current,
+		})
+	}
+	return response
+}
+
+func (s *DailyService) GetDaily(c echo.Context) error {
+	ctx := c.Request().Context()
+	telegramUser := c.Get("telegram_user").(*middleware.TelegramUser)
+
+	userID, err := s.repo.GetUserID(ctx, telegramUser.ID)
+	if err != nil {
+		loger.Logger.Error("Ошибка при получении пользователя",
+			zap.Error(err),
+			zap.Int64("user_id", telegramUser.ID))
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Ошибка при получении пользователя"})
+	}
+	last, err := s.repo.GetLastClaim(ctx, userID)
+	if err != nil && err != sql.ErrNoRows {
+		loger.Logger.Error("Ошибка при получении ежедневной награды",
+			zap.Error(err),
+			zap.Int("user_id", userID))
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Ошибка при получении ежедневной награды"})
+	}
+
+	rules := economy.Get()
+	now := time.Now()
+	streak, claimedToday := currentStreak(last, now, rules.DailyRewardLocation())
+	return c.JSON(http.StatusOK, buildResponse(streak, claimedToday, now, rules))
+}
+
+// ClaimDaily начисляет награду за сегодняшний день. Повторный запрос в тот же
+// календарный день ничего не начисляет и возвращает текущее состояние серии
+func (s *DailyService) ClaimDaily(c echo.Context) error {
+	ctx := c.Request().Context()
+	telegramUser := c.Get("telegram_user").(*middleware.TelegramUser)
+	rules := economy.Get()
+	now := time.Now()
+
+	var response *DailyRewardResponse
+	err := s.repo.WithTx(ctx, func(tx *sql.Tx) error {
+		userID, err := s.repo.LockUser(ctx, tx, telegramUser.ID)
+		if err != nil {
+			return err
+		}
+		last, err := s.repo.GetLastClaimTx(ctx, tx, userID)
+		if err != nil && err != sql.ErrNoRows {
+			return err
+		}
+		streak, claimedToday := currentStreak(last, now, rules.DailyRewardLocation())
+		if claimedToday {
+			response = buildResponse(streak, true, now, rules)
+			return nil
+		}
+
+		streak++
+		claim := ClaimRepo{
+			UserID: userID,
+			Day:    now.In(rules.DailyRewardLocation()),
+			Streak: streak,
+			Reward: rules.DailyReward(streak),
+		}
+		claimID, err := s.repo.CreateClaim(ctx, tx, claim)
+		if err != nil {
+			return err
+		}
+		_, err = s.ledger.Credit(ctx, tx, ledger.Entry{
+			UserID:     userID,
+			Amount:     claim.Reward,
+			Reason:     ledger.ReasonDailyReward,
+			SourceType: ledger.SourceDailyReward,
+			SourceID:   int64(claimID),
+		})
+		if err != nil {
+			return err
+		}
+
+		response = buildResponse(streak, true, now, rules)
+		response.Claimed = claim.Reward
+		return nil
+	})
+	if err != nil {
+		loger.Logger.Error("Ошибка при получении ежедневной награды",
+			zap.Error(err),
+			zap.Int64("user_id", telegramUser.ID))
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Ошибка при получении ежедневной награды"})
+	}
+	return c.JSON(http.StatusOK, response)
+}
+
+// comboDay возвращает календарный день комбо на момент now
+func comboDay(now time.Time, rules *economy.Rules) string {
+	return now.In(rules.DailyRewardLocation()).Format(dayLayout)
+}
+
+// SubscribeEvents отмечает улучшенных работников комбо дня. Улучшение засчитывается в день оплаты,
+// даже если оно завершится на следующий день. Если комбо дня еще не выбрано планировщиком,
+// оно выбирается здесь, чтобы не потерять улучшения, сделанные сразу после полуночи
+func (s *DailyService) SubscribeEvents() {
+	events.Subscribe(events.UpgradePurchased, func(ctx context.Context, tx *sql.Tx, event events.Event) error {
+		workerID, err := strconv.Atoi(event.Attrs["worker_id"])
+		if err != nil {
+			return err
+		}
+		rules := economy.Get()
+		day := comboDay(time.Now(), rules)
+		if err := s.repo.EnsureComboTx(ctx, tx, day, rules.DailyComboReward); err != nil {
+			return err
+		}
+		return s.repo.AddComboProgress(ctx, tx, event.UserID, day, workerID)
+	})
+}
+
+// StartComboScheduler выбирает комбо дня при запуске и затем раз в interval проверяет,
+// не наступил ли новый календарный день
+func (s *DailyService) StartComboScheduler(interval time.Duration) {
+	pick := func() {
+		rules := economy.Get()
+		day := comboDay(time.Now(), rules)
+		if err := s.repo.EnsureCombo(context.Background(), day, rules.DailyComboReward); err != nil {
+			loger.Logger.Error("Ошибка при выборе комбо дня",
+				zap.Error(err),
+				zap.String("day", day))
+		}
+	}
+	pick()
+	go func() {
+		for range time.Tick(interval) {
+			pick()
+		}
+	}()
+}
+
+// buildComboResponse собирает комбо дня для клиента, скрывая работников, которых пользователь еще не улучшил
+func buildComboResponse(day string, reward int64, workers []ComboWorkerRepo, claimed bool, now time.Time, rules *economy.Rules) *DailyComboResponse {
+	response := &DailyComboResponse{
+		Day:         day,
+		Reward:      reward,
+		Slots:       make([]ComboSlot, 0, len(workers)),
+		Claimed:     claimed,
+		NextComboIn: int64(nextDay(now, rules.DailyRewardLocation()).Sub(now).Seconds()),
+	}
+	collected := true
+	for _, worker := range workers {
+		slot := ComboSlot{Found: worker.Found}
+		if worker.Found {
+			worker := worker
+			slot.WorkerID = &worker.ID
+			slot.Name = &worker.Name
+			slot.UrlImage = &worker.UrlImage
+		} else {
+			collected = false
+		}
+		response.Slots = append(response.Slots, slot)
+	}
+	response.CanClaim = collected && !claimed
+	return response
+}
+
+func (s *DailyService) GetDailyCombo(c echo.Context) error {
+	ctx := c.Request().Context()
+	telegramUser := c.Get("telegram_user").(*middleware.TelegramUser)
+	rules := economy.Get()
+	now := time.Now()
+	day := comboDay(now, rules)
+
+	userID, err := s.repo.GetUserID(ctx, telegramUser.ID)
+	if err != nil {
+		loger.Logger.Error("Ошибка при получении пользователя",
+			zap.Error(err),
+			zap.Int64("user_id", telegramUser.ID))
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Ошибка при получении пользователя"})
+	}
+
+	reward, workers, err := s.repo.GetCombo(ctx, userID, day)
+	if err == sql.ErrNoRows {
+		// Новый день мог наступить раньше, чем планировщик выбрал комбо
+		if err = s.repo.EnsureCombo(ctx, day, rules.DailyComboReward); err == nil {
+			reward, workers, err = s.repo.GetCombo(ctx, userID, day)
+		}
+	}
+	var claimed bool
+	if err == nil {
+		claimed, err = s.repo.IsComboClaimed(ctx, userID, day)
+	}
+	switch {
+	case err == sql.ErrNoRows:
+		return c.JSON(http.StatusNotFound, map[string]string{"error": ErrComboNotFound.Error()})
+	case err != nil:
+		loger.Logger.Error("Ошибка при получении комбо дня",
+			zap.Error(err),
+			zap.Int("user_id", userID))
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Ошибка при получении комбо дня"})
+	}
+	return c.JSON(http.StatusOK, buildComboResponse(day, reward, workers, claimed, now, rules))
+}
+
+// ClaimDailyCombo начисляет награду за комбо дня, если пользователь улучшил всех его работников сегодня
+func (s *DailyService) ClaimDailyCombo(c echo.Context) error {
+	ctx := c.Request().Context()
+	telegramUser := c.Get("telegram_user").(*middleware.TelegramUser)
+	rules := economy.Get()
+	now := time.Now()
+	day := comboDay(now, rules)
+
+	var response *DailyComboResponse
+	err := s.repo.WithTx(ctx, func(tx *sql.Tx) error {
+		userID, err := s.repo.LockUser(ctx, tx, telegramUser.ID)
+		if err != nil {
+			return err
+		}
+		reward, workers, err := s.repo.GetComboTx(ctx, tx, userID, day)
+		if err == sql.ErrNoRows {
+			return ErrComboNotFound
+		}
+		if err != nil {
+			return err
+		}
+		for _, worker := range workers {
+			if !worker.Found {
+				return ErrComboNotCollected
+			}
+		}
+
+		claimID, err := s.repo.CreateComboClaim(ctx, tx, userID, day, reward)
+		if err == sql.ErrNoRows {
+			return ErrComboClaimed
+		}
+		if err != nil {
+			return err
+		}
+		_, err = s.ledger.Credit(ctx, tx, ledger.Entry{
+			UserID:     userID,
+			Amount:     reward,
+			Reason:     ledger.ReasonDailyCombo,
+			SourceType: ledger.SourceDailyCombo,
+			SourceID:   int64(claimID),
+		})
+		if err != nil {
+			return err
+		}
+
+		response = buildComboResponse(day, reward, workers, true, now, rules)
+		return nil
+	})
+	switch {
+	case errors.Is(err, ErrComboNotFound):
+		return c.JSON(http.StatusNotFound, map[string]string{"error": err.Error()})
+	case errors.Is(err, ErrComboNotCollected):
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	case errors.Is(err, ErrComboClaimed):
+		return c.JSON(http.StatusConflict, map[string]string{"error": err.Error()})
+	case err != nil:
+		loger.Logger.Error("Ошибка при получении награды за комбо дня",
+			zap.Error(err),
+			zap.Int64("user_id", telegramUser.ID))
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Ошибка при получении награды за комбо дня"})
+	}
+
+	loger.Logger.Info("Награда за комбо дня получена",
+		zap.Int64("user_id", telegramUser.ID),
+		zap.String("day", day),
+		zap.Int64("reward", response.Reward))
+	return c.JSON(http.StatusOK, response)
+}
@@ -0,0 +1,30 @@
+package leaderboard
+
+// Доски рейтинга
+const (
+	BoardBalance       = "balance"
+	BoardProfitPerHour = "profit_per_hour"
+	BoardLevel         = "level"
+)
+
+// Области рейтинга
+const (
+	ScopeGlobal    = "global"
+	ScopeReferrals = "referrals"
+)
+
+type EntryResponse struct {
+	Rank     int    `json:"rank"`
+	Username string `json:"username"`
+	Level    int    `json:"level"`
+	Value    int64  `json:"value"`
+}
+
+type LeaderboardResponse struct {
+	Board   string          `json:"board"`
+	Scope   string          `json:"scope"`
+	Entries []EntryResponse `json:"entries"`
+	// Me — место текущего пользователя, даже если он не попал в топ.
+	// nil, если пользователь появился после последнего пересчета рейтинга
+	Me *EntryResponse `json:"me"`
+}
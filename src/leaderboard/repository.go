@@ -0,0 +1,111 @@
+package leaderboard
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// boardColumns сопоставляет доску с колонками значения и места в leaderboard_ranks
+// и порядком, по которому место считается в материализованном представлении
+var boardColumns = map[string]struct {
+	value string
+	rank  string
+	order string
+}{
+	BoardBalance:       {value: "balance", rank: "balance_rank", order: "balance DESC"},
+	BoardProfitPerHour: {value: "profit_per_hour", rank: "profit_per_hour_rank", order: "profit_per_hour DESC"},
+	BoardLevel:         {value: "level", rank: "level_rank", order: "level DESC, xp DESC"},
+}
+
+type LeaderboardRepository struct {
+	db *sql.DB
+}
+
+func NewLeaderboardRepository(db *sql.DB) *LeaderboardRepository {
+	return &LeaderboardRepository{db: db}
+}
+
+// Refresh пересчитывает рейтинг, не блокируя чтение
+func (r *LeaderboardRepository) Refresh(ctx context.Context) error {
+	_, err := r.db.ExecContext(ctx, "REFRESH MATERIALIZED VIEW CONCURRENTLY leaderboard_ranks")
+	return err
+}
+
+func (r *LeaderboardRepository) GetUserID(ctx context.Context, tg_id int64) (int, error) {
+	var id int
+	err := r.db.QueryRowContext(ctx, "SELECT id FROM users WHERE tg_id = $1", tg_id).Scan(&id)
+	if err != nil {
+		return 0, err
+	}
+	return id, nil
+}
+
+// GetTop возвращает первые limit мест глобального рейтинга по индексу места
+func (r *LeaderboardRepository) GetTop(ctx context.Context, board string, limit int) ([]EntryResponse, error) {
+	columns := boardColumns[board]
+	rows, err := r.db.QueryContext(ctx, fmt.Sprintf(
+		"SELECT %s, username, level, %s FROM leaderboard_ranks ORDER BY %s, user_id LIMIT $1",
+		columns.rank, columns.value, columns.rank,
+	), limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	return scanEntries(rows)
+}
+
+// GetUserRank возвращает место пользователя в глобальном рейтинге
+func (r *LeaderboardRepository) GetUserRank(ctx context.Context, board string, userID int) (*EntryResponse, error) {
+	columns := boardColumns[board]
+	var entry EntryResponse
+	err := r.db.QueryRowContext(ctx, fmt.Sprintf(
+		"SELECT %s, username, level, %s FROM leaderboard_ranks WHERE user_id = $1",
+		columns.rank, columns.value,
+	), userID).Scan(&entry.Rank, &entry.Username, &entry.Level, &entry.Value)
+	if err != nil {
+		return nil, err
+	}
+	return &entry, nil
+}
+
+// GetReferralsRanks возвращает рейтинг среди пользователя и приглашенных им друзей.
+// Набор небольшой, поэтому места считаются на лету по последнему снимку рейтинга
+func (r *LeaderboardRepository) GetReferralsRanks(ctx context.Context, board string, userID int) ([]EntryResponse, []int, error) {
+	columns := boardColumns[board]
+	rows, err := r.db.QueryContext(ctx, fmt.Sprintf(
+		`SELECT RANK() OVER (ORDER BY %s), username, level, %s, user_id
+		FROM leaderboard_ranks
+		WHERE referrer_id = $1 OR user_id = $1
+		ORDER BY 1, user_id`,
+		columns.order, columns.value,
+	), userID)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+	entries := []EntryResponse{}
+	ids := []int{}
+	for rows.Next() {
+		var entry EntryResponse
+		var id int
+		if err := rows.Scan(&entry.Rank, &entry.Username, &entry.Level, &entry.Value, &id); err != nil {
+			return nil, nil, err
+		}
+		entries = append(entries, entry)
+		ids = append(ids, id)
+	}
+	return entries, ids, rows.Err()
+}
+
+func scanEntries(rows *sql.Rows) ([]EntryResponse, error) {
+	entries := []EntryResponse{}
+	for rows.Next() {
+		var entry EntryResponse
+		if err := rows.Scan(&entry.Rank, &entry.Username, &entry.Level, &entry.Value); err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+	return entries, rows.Err()
+}
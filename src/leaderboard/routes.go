@@ -0,0 +1,43 @@
+package leaderboard
+
+import (
+	"api/src/core/config"
+	"api/src/middleware"
+	"github.com/labstack/echo/v4"
+)
+
+type LeaderboardHandler struct {
+	service *LeaderboardService
+	config  *config.Config
+}
+
+func NewLeaderboardHandler(service *LeaderboardService, config *config.Config) *LeaderboardHandler {
+	return &LeaderboardHandler{service: service, config: config}
+}
+
+func RegisterRoutes(e *echo.Echo, service *LeaderboardService, config *config.Config) {
+	handler := NewLeaderboardHandler(service, config)
+
+	leaderboardGroup := e.Group("/leaderboard")
+	leaderboardGroup.Use(middleware.TelegramAuth(middleware.TelegramAuthConfig{
+		BotToken: handler.config.TELEGRAM_BOT_TOKEN,
+	}))
+	leaderboardGroup.GET("", handler.GetLeaderboard)
+}
+
+// @Summary Рейтинг игроков
+// @Description Возвращает топ игроков по балансу, доходу в час или уровню среди всех игроков или среди приглашенных друзей, а также место текущего пользователя. Рейтинг пересчитывается периодически
+// @Tags leaderboard
+// @Accept json
+// @Produce json
+// @Param board query string false "balance, profit_per_hour или level (по умолчанию balance)"
+// @Param scope query string false "global или referrals (по умолчанию global)"
+// @Param limit query int false "Размер топа (по умолчанию и максимум 100)"
+// @Success 200 {object} LeaderboardResponse
+// @Failure 400 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /leaderboard [get]
+// @Security TelegramAuth
+func (h *LeaderboardHandler) GetLeaderboard(c echo.Context) error {
+	return h.service.GetLeaderboard(c)
+}
@@ -0,0 +1,121 @@
+package leaderboard
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"strconv"
+	"time"
+
+	"api/src/core/loger"
+	"api/src/middleware"
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+)
+
+const (
+	defaultLimit = 100
+	maxLimit     = 100
+)
+
+type LeaderboardService struct {
+	repo *LeaderboardRepository
+}
+
+func NewLeaderboardService(repo *LeaderboardRepository) *LeaderboardService {
+	return &LeaderboardService{repo: repo}
+}
+
+// StartRefresher пересчитывает материализованный рейтинг при запуске и затем раз в interval,
+// чтобы запросы не сортировали всю таблицу пользователей
+func (s *LeaderboardService) StartRefresher(interval time.Duration) {
+	refresh := func() {
+		start := time.Now()
+		if err := s.repo.Refresh(context.Background()); err != nil {
+			loger.Logger.Error("Ошибка при пересчете рейтинга", zap.Error(err))
+			return
+		}
+		loger.Logger.Info("Рейтинг пересчитан",
+			zap.Int64("latency", time.Since(start).Milliseconds()))
+	}
+	go func() {
+		refresh()
+		for range time.Tick(interval) {
+			refresh()
+		}
+	}()
+}
+
+func (s *LeaderboardService) GetLeaderboard(c echo.Context) error {
+	ctx := c.Request().Context()
+	telegramUser := c.Get("telegram_user").(*middleware.TelegramUser)
+
+	board := c.QueryParam("board")
+	if board == "" {
+		board = BoardBalance
+	}
+	if _, ok := boardColumns[board]; !ok {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Неизвестный рейтинг"})
+	}
+	scope := c.QueryParam("scope")
+	if scope == "" {
+		scope = ScopeGlobal
+	}
+	if scope != ScopeGlobal && scope != ScopeReferrals {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Неизвестная область рейтинга"})
+	}
+	limit := defaultLimit
+	if v := c.QueryParam("limit"); v != "" {
+		parsed, err := strconv.Atoi(v)
+		if err != nil || parsed <= 0 {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": "Неверный лимит"})
+		}
+		limit = parsed
+	}
+	if limit > maxLimit {
+		limit = maxLimit
+	}
+
+	userID, err := s.repo.GetUserID(ctx, telegramUser.ID)
+	if err != nil {
+		loger.Logger.Error("Ошибка при получении пользователя",
+			zap.Error(err),
+			zap.Int64("user_id", telegramUser.ID))
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Ошибка при получении пользователя"})
+	}
+
+	response := LeaderboardResponse{Board: board, Scope: scope}
+	if scope == ScopeGlobal {
+		response.Entries, err = s.repo.GetTop(ctx, board, limit)
+		if err == nil {
+			response.Me, err = s.repo.GetUserRank(ctx, board, userID)
+			if err == sql.ErrNoRows {
+				err = nil
+			}
+		}
+	} else {
+		var entries []EntryResponse
+		var ids []int
+		entries, ids, err = s.repo.GetReferralsRanks(ctx, board, userID)
+		if err == nil {
+			for i, id := range ids {
+				if id == userID {
+					me := entries[i]
+					response.Me = &me
+				}
+			}
+			if len(entries) > limit {
+				entries = entries[:limit]
+			}
+			response.Entries = entries
+		}
+	}
+	if err != nil {
+		loger.Logger.Error("Ошибка при получении рейтинга",
+			zap.Error(err),
+			zap.String("board", board),
+			zap.String("scope", scope))
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Ошибка при получении рейтинга"})
+	}
+	return c.JSON(http.StatusOK, response)
+}
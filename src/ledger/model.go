@@ -0,0 +1,57 @@
+package ledger
+
+import "time"
+
+// Причины изменения баланса
+const (
+	ReasonTap             = "tap"
+	ReasonProfitPerHour   = "profit_per_hour"
+	ReasonWorkerPurchase  = "worker_purchase"
+	ReasonClothesPurchase = "clothes_purchase"
+	ReasonLevelUp         = "level_up"
+	ReasonReferralBonus   = "referral_bonus"
+	ReasonReferralIncome  = "referral_income"
+	ReasonDailyReward     = "daily_reward"
+	ReasonTaskReward      = "task_reward"
+	ReasonBoostPurchase   = "boost_purchase"
+	ReasonBattleLoot      = "battle_loot"
+	ReasonDailyCombo      = "daily_combo"
+)
+
+// Типы сущностей, из-за которых изменился баланс
+const (
+	SourceWorker      = "worker"
+	SourceClothes     = "clothes"
+	SourceLevel       = "level"
+	SourceUser        = "user"
+	SourceDailyReward = "daily_reward"
+	SourceTask        = "task"
+	SourceBoost       = "boost"
+	SourceBattle      = "battle"
+	SourceDailyCombo  = "daily_combo"
+)
+
+// Entry описывает одно изменение баланса. Amount всегда положительный,
+// направление задается вызовом Credit или Debit
+type Entry struct {
+	UserID     int
+	Amount     int64
+	Reason     string
+	SourceType string
+	SourceID   int64
+}
+
+type LedgerRepo struct {
+	ID           int64     `json:"id"`
+	Amount       int64     `json:"amount"`
+	BalanceAfter int64     `json:"balance_after"`
+	Reason       string    `json:"reason"`
+	SourceType   *string   `json:"source_type"`
+	SourceID     *int64    `json:"source_id"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+type TransactionsResponse struct {
+	Items      []LedgerRepo `json:"items"`
+	NextCursor *int64       `json:"next_cursor"`
+}
@@ -0,0 +1,85 @@
+package ledger
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+)
+
+var ErrInsufficientFunds = errors.New("недостаточно средств")
+
+type LedgerRepository struct {
+	db *sql.DB
+}
+
+func NewLedgerRepository(db *sql.DB) *LedgerRepository {
+	return &LedgerRepository{db: db}
+}
+
+// Apply изменяет баланс на amount и записывает операцию в журнал одним запросом.
+// Списание, уводящее баланс в минус, не выполняется и возвращает ErrInsufficientFunds
+func (r *LedgerRepository) Apply(ctx context.Context, tx *sql.Tx, entry Entry, amount int64) (int64, error) {
+	var balance int64
+	err := tx.QueryRowContext(ctx,
+		`WITH u AS (
+			UPDATE users SET balance = balance + $2
+			WHERE id = $1 AND ($2 >= 0 OR balance + $2 >= 0)
+			RETURNING balance
+		)
+		INSERT INTO balance_ledger (user_id, amount, balance_after, reason, source_type, source_id)
+		SELECT $1, $2, balance, $3, NULLIF($4, ''), NULLIF($5, 0) FROM u
+		RETURNING balance_after`,
+		entry.UserID, amount, entry.Reason, entry.SourceType, entry.SourceID,
+	).Scan(&balance)
+	if err == sql.ErrNoRows {
+		return 0, ErrInsufficientFunds
+	}
+	if err != nil {
+		return 0, err
+	}
+	return balance, nil
+}
+
+func (r *LedgerRepository) GetBalance(ctx context.Context, tx *sql.Tx, userID int) (int64, error) {
+	var balance int64
+	err := tx.QueryRowContext(ctx, "SELECT balance FROM users WHERE id = $1", userID).Scan(&balance)
+	if err != nil {
+		return 0, err
+	}
+	return balance, nil
+}
+
+// GetEntries возвращает операции пользователя от новых к старым с id меньше cursor.
+// cursor = 0 означает первую страницу
+func (r *LedgerRepository) GetEntries(ctx context.Context, userID int, cursor int64, limit int) ([]LedgerRepo, error) {
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT id, amount, balance_after, reason, source_type, source_id, created_at
+		FROM balance_ledger
+		WHERE user_id = $1 AND ($2 = 0 OR id < $2)
+		ORDER BY id DESC
+		LIMIT $3`,
+		userID, cursor, limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	entries := []LedgerRepo{}
+	for rows.Next() {
+		var entry LedgerRepo
+		var sourceType sql.NullString
+		var sourceID sql.NullInt64
+		err := rows.Scan(&entry.ID, &entry.Amount, &entry.BalanceAfter, &entry.Reason, &sourceType, &sourceID, &entry.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		if sourceType.Valid {
+			entry.SourceType = &sourceType.String
+		}
+		if sourceID.Valid {
+			entry.SourceID = &sourceID.Int64
+		}
+		entries = append(entries, entry)
+	}
+	return entries, rows.Err()
+}
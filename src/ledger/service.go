@@ -0,0 +1,62 @@
+package ledger
+
+import (
+	"context"
+	"database/sql"
+)
+
+const (
+	defaultPageSize = 50
+	maxPageSize     = 100
+)
+
+// LedgerService — единственная точка изменения баланса пользователей.
+// Все начисления и списания проходят через журнал balance_ledger
+type LedgerService struct {
+	repo *LedgerRepository
+}
+
+func NewLedgerService(repo *LedgerRepository) *LedgerService {
+	return &LedgerService{repo: repo}
+}
+
+// Credit начисляет entry.Amount на баланс и возвращает новый баланс
+func (s *LedgerService) Credit(ctx context.Context, tx *sql.Tx, entry Entry) (int64, error) {
+	if entry.Amount == 0 {
+		return s.repo.GetBalance(ctx, tx, entry.UserID)
+	}
+	return s.repo.Apply(ctx, tx, entry, entry.Amount)
+}
+
+// Debit списывает entry.Amount с баланса и возвращает новый баланс.
+// Если средств не хватает, возвращает ErrInsufficientFunds
+func (s *LedgerService) Debit(ctx context.Context, tx *sql.Tx, entry Entry) (int64, error) {
+	if entry.Amount == 0 {
+		return s.repo.GetBalance(ctx, tx, entry.UserID)
+	}
+	return s.repo.Apply(ctx, tx, entry, -entry.Amount)
+}
+
+// GetTransactions возвращает страницу истории операций пользователя
+func (s *LedgerService) GetTransactions(ctx context.Context, userID int, cursor int64, limit int) (*TransactionsResponse, error) {
+	if limit <= 0 {
+		limit = defaultPageSize
+	}
+	if limit > maxPageSize {
+		limit = maxPageSize
+	}
+
+	// Берем на одну запись больше, чтобы понять, есть ли следующая страница
+	entries, err := s.repo.GetEntries(ctx, userID, cursor, limit+1)
+	if err != nil {
+		return nil, err
+	}
+
+	response := &TransactionsResponse{Items: entries}
+	if len(entries) > limit {
+		response.Items = entries[:limit]
+		next := entries[limit-1].ID
+		response.NextCursor = &next
+	}
+	return response, nil
+}
@@ -0,0 +1,24 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+)
+
+// AdminAuth middleware пропускает только запросы с заголовком X-Admin-Token, равным token.
+// Пустой token закрывает доступ полностью
+func AdminAuth(token string) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			header := c.Request().Header.Get("X-Admin-Token")
+			if token == "" || subtle.ConstantTimeCompare([]byte(header), []byte(token)) != 1 {
+				return c.JSON(http.StatusUnauthorized, map[string]string{
+					"error": "Invalid admin token",
+				})
+			}
+			return next(c)
+		}
+	}
+}
@@ -61,6 +61,8 @@ func TelegramAuth(config TelegramAuthConfig) echo.MiddlewareFunc {
 
 			// Устанавливаем структуру пользователя в контекст
 			c.Set("telegram_user", &telegramUser)
+			// start_param передается, если Mini App открыт по ссылке с параметром startapp
+			c.Set("start_param", data["start_param"])
 
 			return next(c)
 		}
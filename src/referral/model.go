@@ -0,0 +1,37 @@
+package referral
+
+import "time"
+
+// StartParamPrefix — префикс start_param в ссылке-приглашении Telegram
+const StartParamPrefix = "ref_"
+
+type ReferralRepo struct {
+	ID         int   `json:"id"`
+	ReferrerID int   `json:"referrer_id"`
+	RefereeID  int   `json:"referee_id"`
+	Earned     int64 `json:"earned"`
+	// ShareRemainder — дробная часть отчислений в сотых долях монеты
+	ShareRemainder int64     `json:"-"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+// FriendResponse описывает приглашенного друга
+type FriendResponse struct {
+	Username string `json:"username"`
+	Level    int    `json:"level"`
+	// Earned — сколько пригласивший получил с дохода этого друга
+	Earned   int64     `json:"earned"`
+	JoinedAt time.Time `json:"joined_at"`
+}
+
+type ReferralsResponse struct {
+	Code       string `json:"code"`
+	StartParam string `json:"start_param"`
+	// Link заполняется, если задан TELEGRAM_APP_URL
+	Link        string `json:"link,omitempty"`
+	Count       int    `json:"count"`
+	TotalEarned int64  `json:"total_earned"`
+	// SharePercent — доля дохода друзей, которую получает пригласивший
+	SharePercent int              `json:"share_percent"`
+	Friends      []FriendResponse `json:"friends"`
+}
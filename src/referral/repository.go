@@ -0,0 +1,88 @@
+package referral
+
+import (
+	"context"
+	"database/sql"
+)
+
+type ReferralRepository struct {
+	db *sql.DB
+}
+
+func NewReferralRepository(db *sql.DB) *ReferralRepository {
+	return &ReferralRepository{db: db}
+}
+
+// GetUserIDByCode возвращает id владельца реферального кода
+func (r *ReferralRepository) GetUserIDByCode(ctx context.Context, tx *sql.Tx, code string) (int, error) {
+	var id int
+	err := tx.QueryRowContext(ctx, "SELECT id FROM users WHERE referral_code = $1", code).Scan(&id)
+	if err != nil {
+		return 0, err
+	}
+	return id, nil
+}
+
+// CreateReferral записывает приглашение. Пользователя можно пригласить только один раз
+func (r *ReferralRepository) CreateReferral(ctx context.Context, tx *sql.Tx, referrerID int, refereeID int) error {
+	_, err := tx.ExecContext(ctx, "INSERT INTO referrals (referrer_id, referee_id) VALUES ($1, $2)", referrerID, refereeID)
+	if err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(ctx, "UPDATE users SET referrer_id = $1 WHERE id = $2", referrerID, refereeID)
+	return err
+}
+
+// GetReferrer возвращает приглашение, по которому пришел пользователь
+func (r *ReferralRepository) GetReferrer(ctx context.Context, tx *sql.Tx, refereeID int) (*ReferralRepo, error) {
+	var referral ReferralRepo
+	err := tx.QueryRowContext(ctx,
+		"SELECT id, referrer_id, referee_id, earned, share_remainder, created_at FROM referrals WHERE referee_id = $1 FOR UPDATE",
+		refereeID,
+	).Scan(&referral.ID, &referral.ReferrerID, &referral.RefereeID, &referral.Earned, &referral.ShareRemainder, &referral.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &referral, nil
+}
+
+func (r *ReferralRepository) AddEarned(ctx context.Context, tx *sql.Tx, id int, amount int64, remainder int64) error {
+	_, err := tx.ExecContext(ctx, "UPDATE referrals SET earned = earned + $1, share_remainder = $2 WHERE id = $3", amount, remainder, id)
+	return err
+}
+
+// GetUser возвращает id и реферальный код пользователя по Telegram ID
+func (r *ReferralRepository) GetUser(ctx context.Context, tg_id int64) (int, string, error) {
+	var id int
+	var code string
+	err := r.db.QueryRowContext(ctx, "SELECT id, referral_code FROM users WHERE tg_id = $1", tg_id).Scan(&id, &code)
+	if err != nil {
+		return 0, "", err
+	}
+	return id, code, nil
+}
+
+// GetFriends возвращает приглашенных пользователем друзей, новые первыми
+func (r *ReferralRepository) GetFriends(ctx context.Context, referrerID int) ([]FriendResponse, error) {
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT u.username, u.level, rf.earned, rf.created_at
+		FROM referrals rf
+		JOIN users u ON u.id = rf.referee_id
+		WHERE rf.referrer_id = $1
+		ORDER BY rf.created_at DESC`,
+		referrerID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	friends := []FriendResponse{}
+	for rows.Next() {
+		var friend FriendResponse
+		if err := rows.Scan(&friend.Username, &friend.Level, &friend.Earned, &friend.JoinedAt); err != nil {
+			return nil, err
+		}
+		friends = append(friends, friend)
+	}
+	return friends, rows.Err()
+}
@@ -0,0 +1,39 @@
+package referral
+
+import (
+	"api/src/core/config"
+	"api/src/middleware"
+	"github.com/labstack/echo/v4"
+)
+
+type ReferralHandler struct {
+	service *ReferralService
+	config  *config.Config
+}
+
+func NewReferralHandler(service *ReferralService, config *config.Config) *ReferralHandler {
+	return &ReferralHandler{service: service, config: config}
+}
+
+func RegisterRoutes(e *echo.Echo, service *ReferralService, config *config.Config) {
+	handler := NewReferralHandler(service, config)
+
+	referralGroup := e.Group("/user/referrals")
+	referralGroup.Use(middleware.TelegramAuth(middleware.TelegramAuthConfig{
+		BotToken: handler.config.TELEGRAM_BOT_TOKEN,
+	}))
+	referralGroup.GET("", handler.GetReferrals)
+}
+
+// @Summary Приглашенные друзья
+// @Description Возвращает реферальный код, ссылку-приглашение и список приглашенных друзей с их уровнем и отчислениями пригласившему
+// @Tags user
+// @Accept json
+// @Produce json
+// @Success 200 {object} ReferralsResponse
+// @Failure 500 {object} map[string]string
+// @Router /user/referrals [get]
+// @Security TelegramAuth
+func (h *ReferralHandler) GetReferrals(c echo.Context) error {
+	return h.service.GetReferrals(c)
+}
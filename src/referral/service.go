@@ -0,0 +1,159 @@
+package referral
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/base32"
+	"net/http"
+	"strings"
+
+	"api/src/core/config"
+	"api/src/core/economy"
+	"api/src/core/events"
+	"api/src/core/loger"
+	"api/src/ledger"
+	"api/src/middleware"
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+)
+
+type ReferralService struct {
+	repo   *ReferralRepository
+	ledger *ledger.LedgerService
+	config *config.Config
+}
+
+func NewReferralService(repo *ReferralRepository, ledger *ledger.LedgerService, config *config.Config) *ReferralService {
+	return &ReferralService{repo: repo, ledger: ledger, config: config}
+}
+
+// NewCode генерирует случайный реферальный код, допустимый в start_param Telegram
+func NewCode() (string, error) {
+	buf := make([]byte, 5)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return strings.ToLower(base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(buf)), nil
+}
+
+// Attach связывает нового пользователя с пригласившим по start_param и выдает бонусы обоим.
+// Неизвестный или чужой start_param игнорируется
+func (s *ReferralService) Attach(ctx context.Context, tx *sql.Tx, refereeID int, startParam string) error {
+	if !strings.HasPrefix(startParam, StartParamPrefix) {
+		return nil
+	}
+	code := strings.TrimPrefix(startParam, StartParamPrefix)
+
+	referrerID, err := s.repo.GetUserIDByCode(ctx, tx, code)
+	if err == sql.ErrNoRows {
+		loger.Logger.Info("Неизвестный реферальный код",
+			zap.String("code", code),
+			zap.Int("user_id", refereeID))
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if referrerID == refereeID {
+		return nil
+	}
+
+	if err := s.repo.CreateReferral(ctx, tx, referrerID, refereeID); err != nil {
+		return err
+	}
+
+	rules := economy.Get()
+	_, err = s.ledger.Credit(ctx, tx, ledger.Entry{
+		UserID:     referrerID,
+		Amount:     rules.ReferralInviterBonus,
+		Reason:     ledger.ReasonReferralBonus,
+		SourceType: ledger.SourceUser,
+		SourceID:   int64(refereeID),
+	})
+	if err != nil {
+		return err
+	}
+	_, err = s.ledger.Credit(ctx, tx, ledger.Entry{
+		UserID:     refereeID,
+		Amount:     rules.ReferralInviteeBonus,
+		Reason:     ledger.ReasonReferralBonus,
+		SourceType: ledger.SourceUser,
+		SourceID:   int64(referrerID),
+	})
+	if err != nil {
+		return err
+	}
+
+	loger.Logger.Info("Пользователь пришел по приглашению",
+		zap.Int("referrer_id", referrerID),
+		zap.Int("referee_id", refereeID))
+	return events.Publish(ctx, tx, events.Event{Type: events.FriendInvited, UserID: referrerID, Amount: 1})
+}
+
+// ShareIncome отчисляет пригласившему долю пассивного дохода друга.
+// Доход самого друга не уменьшается, дробная часть отчисления переносится
+func (s *ReferralService) ShareIncome(ctx context.Context, tx *sql.Tx, refereeID int, income int64) error {
+	percent := economy.Get().ReferralIncomeSharePercent
+	if income <= 0 || percent <= 0 {
+		return nil
+	}
+	referral, err := s.repo.GetReferrer(ctx, tx, refereeID)
+	if err == sql.ErrNoRows {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	units := income*int64(percent) + referral.ShareRemainder
+	share, remainder := units/100, units%100
+	if share > 0 {
+		_, err = s.ledger.Credit(ctx, tx, ledger.Entry{
+			UserID:     referral.ReferrerID,
+			Amount:     share,
+			Reason:     ledger.ReasonReferralIncome,
+			SourceType: ledger.SourceUser,
+			SourceID:   int64(refereeID),
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return s.repo.AddEarned(ctx, tx, referral.ID, share, remainder)
+}
+
+func (s *ReferralService) GetReferrals(c echo.Context) error {
+	ctx := c.Request().Context()
+	telegramUser := c.Get("telegram_user").(*middleware.TelegramUser)
+
+	userID, code, err := s.repo.GetUser(ctx, telegramUser.ID)
+	if err != nil {
+		loger.Logger.Error("Ошибка при получении пользователя",
+			zap.Error(err),
+			zap.Int64("user_id", telegramUser.ID))
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Ошибка при получении пользователя"})
+	}
+	friends, err := s.repo.GetFriends(ctx, userID)
+	if err != nil {
+		loger.Logger.Error("Ошибка при получении приглашенных друзей",
+			zap.Error(err),
+			zap.Int("user_id", userID))
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Ошибка при получении приглашенных друзей"})
+	}
+
+	response := ReferralsResponse{
+		Code:         code,
+		StartParam:   StartParamPrefix + code,
+		Count:        len(friends),
+		SharePercent: economy.Get().ReferralIncomeSharePercent,
+		Friends:      friends,
+	}
+	if s.config.TELEGRAM_APP_URL != "" {
+		response.Link = s.config.TELEGRAM_APP_URL + "?startapp=" + response.StartParam
+	}
+	for _, friend := range friends {
+		response.TotalEarned += friend.Earned
+	}
+	return c.JSON(http.StatusOK, response)
+}
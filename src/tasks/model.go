@@ -0,0 +1,65 @@
+package tasks
+
+import "time"
+
+// Типы заданий
+const (
+	TypeTaps          = "taps"
+	TypeReachLevel    = "reach_level"
+	TypeBuyWorker     = "buy_worker" // только покупка новых работников, без улучшений
+	TypeBuyClothes    = "buy_clothes"
+	TypeInviteFriends = "invite_friends"
+	TypeJoinChannel   = "join_channel"
+)
+
+type TaskRepo struct {
+	ID          int    `json:"id"`
+	Title       string `json:"title"`
+	Description string `json:"description"`
+	Type        string `json:"type"`
+	Target      int64  `json:"target"`
+	// WorkerType ограничивает задание buy_worker типом работника, например army
+	WorkerType *string `json:"worker_type"`
+	// Channel — @username канала для задания join_channel
+	Channel  *string `json:"channel"`
+	Reward   int64   `json:"reward"`
+	IsActive bool    `json:"is_active"`
+}
+
+// userState — текущее состояние пользователя, от которого считается прогресс заданий, созданных
+// после того, как пользователь уже повысил уровень, купил работников или пригласил друзей
+type userState struct {
+	Level   int
+	Friends int64
+	// Workers — число купленных работников по типам
+	Workers map[string]int64
+}
+
+type UserTaskRepo struct {
+	Progress  int64      `json:"progress"`
+	ClaimedAt *time.Time `json:"claimed_at"`
+}
+
+type TaskResponse struct {
+	ID          int     `json:"id"`
+	Title       string  `json:"title"`
+	Description string  `json:"description"`
+	Type        string  `json:"type"`
+	Target      int64   `json:"target"`
+	Progress    int64   `json:"progress"`
+	Reward      int64   `json:"reward"`
+	Channel     *string `json:"channel"`
+	// Completed — условие выполнено. Для join_channel проверяется только при получении награды
+	Completed bool `json:"completed"`
+	Claimed   bool `json:"claimed"`
+}
+
+type CreateTaskRequest struct {
+	Title       string  `json:"title"`
+	Description string  `json:"description"`
+	Type        string  `json:"type"`
+	Target      int64   `json:"target"`
+	WorkerType  *string `json:"worker_type"`
+	Channel     *string `json:"channel"`
+	Reward      int64   `json:"reward"`
+}
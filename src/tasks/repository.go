@@ -0,0 +1,210 @@
+package tasks
+
+import (
+	"api/db"
+	"context"
+	"database/sql"
+)
+
+const taskColumns = "id, title, description, type, target, worker_type, channel, reward, is_active"
+
+type TasksRepository struct {
+	db *sql.DB
+}
+
+func NewTasksRepository(db *sql.DB) *TasksRepository {
+	return &TasksRepository{db: db}
+}
+
+// WithTx выполняет fn в одной транзакции базы данных
+func (r *TasksRepository) WithTx(ctx context.Context, fn func(tx *sql.Tx) error) error {
+	return db.WithTx(ctx, r.db, fn)
+}
+
+type queryer interface {
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+func scanTask(row interface{ Scan(dest ...any) error }) (*TaskRepo, error) {
+	var task TaskRepo
+	var workerType, channel sql.NullString
+	err := row.Scan(&task.ID, &task.Title, &task.Description, &task.Type, &task.Target, &workerType, &channel, &task.Reward, &task.IsActive)
+	if err != nil {
+		return nil, err
+	}
+	if workerType.Valid {
+		task.WorkerType = &workerType.String
+	}
+	if channel.Valid {
+		task.Channel = &channel.String
+	}
+	return &task, nil
+}
+
+// AddProgress увеличивает прогресс всех активных заданий типа taskType, подходящих под workerType.
+// При keepMax прогресс не суммируется, а поднимается до amount
+func (r *TasksRepository) AddProgress(ctx context.Context, tx *sql.Tx, userID int, taskType string, workerType string, amount int64, keepMax bool) error {
+	_, err := tx.ExecContext(ctx,
+		`INSERT INTO user_tasks (user_id, task_id, progress)
+		SELECT $1, id, $2 FROM tasks
+		WHERE is_active AND type = $3 AND (worker_type IS NULL OR worker_type = $4)
+		ON CONFLICT (user_id, task_id) DO UPDATE SET progress = CASE
+			WHEN $5 THEN GREATEST(user_tasks.progress, EXCLUDED.progress)
+			ELSE user_tasks.progress + EXCLUDED.progress
+		END
+		WHERE user_tasks.claimed_at IS NULL`,
+		userID, amount, taskType, workerType, keepMax,
+	)
+	return err
+}
+
+// GetUserTasks возвращает активные задания с прогрессом пользователя
+func (r *TasksRepository) GetUserTasks(ctx context.Context, userID int) ([]TaskRepo, []UserTaskRepo, error) {
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT t.id, t.title, t.description, t.type, t.target, t.worker_type, t.channel, t.reward, t.is_active,
+			COALESCE(ut.progress, 0), ut.claimed_at
+		FROM tasks t
+		LEFT JOIN user_tasks ut ON ut.task_id = t.id AND ut.user_id = $1
+		WHERE t.is_active
+		ORDER BY t.id`,
+		userID,
+	)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+	tasks := []TaskRepo{}
+	progress := []UserTaskRepo{}
+	for rows.Next() {
+		var task TaskRepo
+		var userTask UserTaskRepo
+		var workerType, channel sql.NullString
+		var claimedAt sql.NullTime
+		err := rows.Scan(&task.ID, &task.Title, &task.Description, &task.Type, &task.Target, &workerType, &channel, &task.Reward, &task.IsActive,
+			&userTask.Progress, &claimedAt)
+		if err != nil {
+			return nil, nil, err
+		}
+		if workerType.Valid {
+			task.WorkerType = &workerType.String
+		}
+		if channel.Valid {
+			task.Channel = &channel.String
+		}
+		if claimedAt.Valid {
+			userTask.ClaimedAt = &claimedAt.Time
+		}
+		tasks = append(tasks, task)
+		progress = append(progress, userTask)
+	}
+	return tasks, progress, rows.Err()
+}
+
+func (r *TasksRepository) GetTask(ctx context.Context, id int) (*TaskRepo, error) {
+	return scanTask(r.db.QueryRowContext(ctx, "SELECT "+taskColumns+" FROM tasks WHERE id = $1 AND is_active", id))
+}
+
+// GetUserTask возвращает прогресс пользователя по заданию и блокирует его строку
+func (r *TasksRepository) GetUserTask(ctx context.Context, tx *sql.Tx, userID int, taskID int) (*UserTaskRepo, error) {
+	var userTask UserTaskRepo
+	var claimedAt sql.NullTime
+	err := tx.QueryRowContext(ctx,
+		"SELECT progress, claimed_at FROM user_tasks WHERE user_id = $1 AND task_id = $2 FOR UPDATE",
+		userID, taskID,
+	).Scan(&userTask.Progress, &claimedAt)
+	if err != nil {
+		return nil, err
+	}
+	if claimedAt.Valid {
+		userTask.ClaimedAt = &claimedAt.Time
+	}
+	return &userTask, nil
+}
+
+func (r *TasksRepository) MarkClaimed(ctx context.Context, tx *sql.Tx, userID int, taskID int) error {
+	_, err := tx.ExecContext(ctx,
+		`INSERT INTO user_tasks (user_id, task_id, claimed_at) VALUES ($1, $2, NOW())
+		ON CONFLICT (user_id, task_id) DO UPDATE SET claimed_at = NOW()`,
+		userID, taskID,
+	)
+	return err
+}
+
+// LockUser блокирует строку пользователя до конца транзакции и возвращает его id и уровень
+func (r *TasksRepository) LockUser(ctx context.Context, tx *sql.Tx, tg_id int64) (int, int, error) {
+	var id, level int
+	err := tx.QueryRowContext(ctx, "SELECT id, level FROM users WHERE tg_id = $1 FOR UPDATE", tg_id).Scan(&id, &level)
+	if err != nil {
+		return 0, 0, err
+	}
+	return id, level, nil
+}
+
+func (r *TasksRepository) GetUser(ctx context.Context, tg_id int64) (int, int, error) {
+	var id, level int
+	err := r.db.QueryRowContext(ctx, "SELECT id, level FROM users WHERE tg_id = $1", tg_id).Scan(&id, &level)
+	if err != nil {
+		return 0, 0, err
+	}
+	return id, level, nil
+}
+
+// GetUserState возвращает число приглашенных друзей и купленных работников по типам. Уровень не заполняется
+func (r *TasksRepository) GetUserState(ctx context.Context, userID int) (*userState, error) {
+	return getUserState(ctx, r.db, userID)
+}
+
+func (r *TasksRepository) GetUserStateTx(ctx context.Context, tx *sql.Tx, userID int) (*userState, error) {
+	return getUserState(ctx, tx, userID)
+}
+
+func getUserState(ctx context.Context, q queryer, userID int) (*userState, error) {
+	state := &userState{Workers: map[string]int64{}}
+	err := q.QueryRowContext(ctx, "SELECT COUNT(*) FROM referrals WHERE referrer_id = $1", userID).Scan(&state.Friends)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := q.QueryContext(ctx,
+		`SELECT w.type, COUNT(*) FROM user_workers uw
+		JOIN workers w ON w.id = uw.id_worker
+		WHERE uw.id_user = $1
+		GROUP BY w.type`,
+		userID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var workerType string
+		var count int64
+		if err := rows.Scan(&workerType, &count); err != nil {
+			return nil, err
+		}
+		state.Workers[workerType] = count
+	}
+	return state, rows.Err()
+}
+
+func (r *TasksRepository) CreateTask(ctx context.Context, req CreateTaskRequest) (*TaskRepo, error) {
+	return scanTask(r.db.QueryRowContext(ctx,
+		`INSERT INTO tasks (title, description, type, target, worker_type, channel, reward)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING `+taskColumns,
+		req.Title, req.Description, req.Type, req.Target, req.WorkerType, req.Channel, req.Reward,
+	))
+}
+
+// DeactivateTask скрывает задание. Уже полученные награды сохраняются
+func (r *TasksRepository) DeactivateTask(ctx context.Context, id int) (bool, error) {
+	res, err := r.db.ExecContext(ctx, "UPDATE tasks SET is_active = FALSE WHERE id = $1 AND is_active", id)
+	if err != nil {
+		return false, err
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return affected > 0, nil
+}
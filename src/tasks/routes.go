@@ -0,0 +1,93 @@
+package tasks
+
+import (
+	"api/src/core/config"
+	"api/src/middleware"
+	"github.com/labstack/echo/v4"
+)
+
+type TasksHandler struct {
+	service *TasksService
+	config  *config.Config
+}
+
+func NewTasksHandler(service *TasksService, config *config.Config) *TasksHandler {
+	return &TasksHandler{service: service, config: config}
+}
+
+func RegisterRoutes(e *echo.Echo, service *TasksService, config *config.Config) {
+	handler := NewTasksHandler(service, config)
+
+	tasksGroup := e.Group("/tasks")
+	tasksGroup.Use(middleware.TelegramAuth(middleware.TelegramAuthConfig{
+		BotToken: handler.config.TELEGRAM_BOT_TOKEN,
+	}))
+	tasksGroup.GET("", handler.GetTasks)
+	tasksGroup.POST("/:id/claim", handler.ClaimTask)
+
+	adminGroup := e.Group("/admin/tasks")
+	adminGroup.Use(middleware.AdminAuth(handler.config.ADMIN_TOKEN))
+	adminGroup.POST("", handler.CreateTask)
+	adminGroup.DELETE("/:id", handler.DeleteTask)
+}
+
+// @Summary Список заданий
+// @Description Возвращает активные задания с прогрессом пользователя
+// @Tags tasks
+// @Accept json
+// @Produce json
+// @Success 200 {array} TaskResponse
+// @Failure 500 {object} map[string]string
+// @Router /tasks [get]
+// @Security TelegramAuth
+func (h *TasksHandler) GetTasks(c echo.Context) error {
+	return h.service.GetTasks(c)
+}
+
+// @Summary Забрать награду за задание
+// @Description Проверяет выполнение задания и начисляет награду. Для join_channel подписка проверяется через Telegram
+// @Tags tasks
+// @Accept json
+// @Produce json
+// @Param id path int true "ID задания"
+// @Success 200 {object} TaskResponse
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Failure 502 {object} map[string]string
+// @Router /tasks/{id}/claim [post]
+// @Security TelegramAuth
+func (h *TasksHandler) ClaimTask(c echo.Context) error {
+	return h.service.ClaimTask(c)
+}
+
+// @Summary Создать задание
+// @Description Создает новое задание. Требует заголовок X-Admin-Token
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param request body CreateTaskRequest true "Задание"
+// @Success 201 {object} TaskRepo
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /admin/tasks [post]
+// @Security AdminAuth
+func (h *TasksHandler) CreateTask(c echo.Context) error {
+	return h.service.CreateTask(c)
+}
+
+// @Summary Удалить задание
+// @Description Скрывает задание из списка. Требует заголовок X-Admin-Token
+// @Tags admin
+// @Param id path int true "ID задания"
+// @Success 204
+// @Failure 401 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /admin/tasks/{id} [delete]
+// @Security AdminAuth
+func (h *TasksHandler) DeleteTask(c echo.Context) error {
+	return h.service.DeleteTask(c)
+}
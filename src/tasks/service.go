@@ -0,0 +1,344 @@
+package tasks
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"api/src/core/config"
+	"api/src/core/events"
+	"api/src/core/loger"
+	"api/src/ledger"
+	"api/src/middleware"
+	"github.com/labstack/echo/v4"
+	"go.uber.org/zap"
+)
+
+var (
+	ErrAlreadyClaimed = errors.New("награда за задание уже получена")
+	ErrNotCompleted   = errors.New("задание еще не выполнено")
+)
+
+var taskTypes = map[string]bool{
+	TypeTaps:          true,
+	TypeReachLevel:    true,
+	TypeBuyWorker:     true,
+	TypeBuyClothes:    true,
+	TypeInviteFriends: true,
+	TypeJoinChannel:   true,
+}
+
+type TasksService struct {
+	repo   *TasksRepository
+	ledger *ledger.LedgerService
+	config *config.Config
+	client *http.Client
+}
+
+func NewTasksService(repo *TasksRepository, ledger *ledger.LedgerService, config *config.Config) *TasksService {
+	return &TasksService{
+		repo:   repo,
+		ledger: ledger,
+		config: config,
+		client: &http.Client{Timeout: 5 * time.Second},
+	}
+}
+
+// SubscribeEvents подписывает прогресс заданий на игровые события
+func (s *TasksService) SubscribeEvents() {
+	events.Subscribe(events.TapsMade, s.progressHandler(TypeTaps, false))
+	events.Subscribe(events.LevelReached, s.progressHandler(TypeReachLevel, true))
+	events.Subscribe(events.WorkerBought, s.progressHandler(TypeBuyWorker, false))
+	events.Subscribe(events.ClothesBought, s.progressHandler(TypeBuyClothes, false))
+	events.Subscribe(events.FriendInvited, s.progressHandler(TypeInviteFriends, false))
+}
+
+func (s *TasksService) progressHandler(taskType string, keepMax bool) events.Handler {
+	return func(ctx context.Context, tx *sql.Tx, event events.Event) error {
+		amount := event.Amount
+		if taskType == TypeBuyWorker {
+			// WorkerBought публикуется и при улучшении, а задание считает только покупку первого уровня
+			if event.Amount != 1 {
+				return nil
+			}
+		} else if amount <= 0 {
+			amount = 1
+		}
+		return s.repo.AddProgress(ctx, tx, event.UserID, taskType, event.Attrs["worker_type"], amount, keepMax)
+	}
+}
+
+// progress возвращает прогресс по заданию с учетом текущего состояния пользователя,
+// чтобы новое задание учитывало уровень, работников и друзей, набранных до его создания
+func progress(task TaskRepo, userTask UserTaskRepo, state *userState) int64 {
+	var current int64
+	switch task.Type {
+	case TypeReachLevel:
+		current = int64(state.Level)
+	case TypeBuyWorker:
+		if task.WorkerType != nil {
+			current = state.Workers[*task.WorkerType]
+		} else {
+			for _, count := range state.Workers {
+				current += count
+			}
+		}
+	case TypeInviteFriends:
+		current = state.Friends
+	}
+	if current > userTask.Progress {
+		return current
+	}
+	return userTask.Progress
+}
+
+func buildResponse(task TaskRepo, userTask UserTaskRepo, state *userState) TaskResponse {
+	current := progress(task, userTask, state)
+	response := TaskResponse{
+		ID:          task.ID,
+		Title:       task.Title,
+		Description: task.Description,
+		Type:        task.Type,
+		Target:      task.Target,
+		Progress:    current,
+		Reward:      task.Reward,
+		Channel:     task.Channel,
+		Claimed:     userTask.ClaimedAt != nil,
+	}
+	if task.Type != TypeJoinChannel {
+		response.Completed = current >= task.Target
+		if response.Progress > task.Target {
+			response.Progress = task.Target
+		}
+	}
+	if response.Claimed {
+		response.Completed = true
+		response.Progress = task.Target
+	}
+	return response
+}
+
+// isChannelMember проверяет через Bot API, что пользователь состоит в канале
+func (s *TasksService) isChannelMember(ctx context.Context, channel string, tgID int64) (bool, error) {
+	query := url.Values{}
+	query.Set("chat_id", channel)
+	query.Set("user_id", strconv.FormatInt(tgID, 10))
+	endpoint := fmt.Sprintf("https://api.telegram.org/bot%s/getChatMember?%s", s.config.TELEGRAM_BOT_TOKEN, query.Encode())
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return false, err
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+
+	var result struct {
+		Ok          bool   `json:"ok"`
+		Description string `json:"description"`
+		Result      struct {
+			Status   string `json:"status"`
+			IsMember bool   `json:"is_member"`
+		} `json:"result"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return false, err
+	}
+	if !result.Ok {
+		// Telegram отвечает ошибкой, если пользователь ни разу не заходил в канал
+		if strings.Contains(result.Description, "user not found") {
+			return false, nil
+		}
+		return false, fmt.Errorf("getChatMember: %s", result.Description)
+	}
+	switch result.Result.Status {
+	case "creator", "administrator", "member":
+		return true, nil
+	case "restricted":
+		return result.Result.IsMember, nil
+	default:
+		return false, nil
+	}
+}
+
+func (s *TasksService) GetTasks(c echo.Context) error {
+	ctx := c.Request().Context()
+	telegramUser := c.Get("telegram_user").(*middleware.TelegramUser)
+
+	userID, level, err := s.repo.GetUser(ctx, telegramUser.ID)
+	if err != nil {
+		loger.Logger.Error("Ошибка при получении пользователя",
+			zap.Error(err),
+			zap.Int64("user_id", telegramUser.ID))
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Ошибка при получении пользователя"})
+	}
+	tasks, userTasks, err := s.repo.GetUserTasks(ctx, userID)
+	if err != nil {
+		loger.Logger.Error("Ошибка при получении заданий",
+			zap.Error(err),
+			zap.Int("user_id", userID))
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Ошибка при получении заданий"})
+	}
+	state, err := s.repo.GetUserState(ctx, userID)
+	if err != nil {
+		loger.Logger.Error("Ошибка при получении заданий",
+			zap.Error(err),
+			zap.Int("user_id", userID))
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Ошибка при получении заданий"})
+	}
+	state.Level = level
+
+	response := make([]TaskResponse, 0, len(tasks))
+	for i, task := range tasks {
+		response = append(response, buildResponse(task, userTasks[i], state))
+	}
+	return c.JSON(http.StatusOK, response)
+}
+
+// ClaimTask проверяет выполнение задания и начисляет награду. Награда выдается один раз
+func (s *TasksService) ClaimTask(c echo.Context) error {
+	ctx := c.Request().Context()
+	telegramUser := c.Get("telegram_user").(*middleware.TelegramUser)
+
+	taskID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Неверный ID задания"})
+	}
+	task, err := s.repo.GetTask(ctx, taskID)
+	if err == sql.ErrNoRows {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "Задание не найдено"})
+	}
+	if err != nil {
+		loger.Logger.Error("Ошибка при получении задания",
+			zap.Error(err),
+			zap.Int("task_id", taskID))
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Ошибка при получении задания"})
+	}
+
+	// Подписку проверяем до транзакции, чтобы не держать блокировку на время запроса к Telegram
+	if task.Type == TypeJoinChannel {
+		if task.Channel == nil {
+			return c.JSON(http.StatusInternalServerError, map[string]string{"error": "У задания не указан канал"})
+		}
+		member, err := s.isChannelMember(ctx, *task.Channel, telegramUser.ID)
+		if err != nil {
+			loger.Logger.Error("Ошибка при проверке подписки на канал",
+				zap.Error(err),
+				zap.String("channel", *task.Channel),
+				zap.Int64("user_id", telegramUser.ID))
+			return c.JSON(http.StatusBadGateway, map[string]string{"error": "Не удалось проверить подписку на канал"})
+		}
+		if !member {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": ErrNotCompleted.Error()})
+		}
+	}
+
+	var response TaskResponse
+	err = s.repo.WithTx(ctx, func(tx *sql.Tx) error {
+		userID, level, err := s.repo.LockUser(ctx, tx, telegramUser.ID)
+		if err != nil {
+			return err
+		}
+		userTask, err := s.repo.GetUserTask(ctx, tx, userID, task.ID)
+		if err == sql.ErrNoRows {
+			userTask = &UserTaskRepo{}
+		} else if err != nil {
+			return err
+		}
+		if userTask.ClaimedAt != nil {
+			return ErrAlreadyClaimed
+		}
+		state, err := s.repo.GetUserStateTx(ctx, tx, userID)
+		if err != nil {
+			return err
+		}
+		state.Level = level
+		if task.Type != TypeJoinChannel && progress(*task, *userTask, state) < task.Target {
+			return ErrNotCompleted
+		}
+
+		if err := s.repo.MarkClaimed(ctx, tx, userID, task.ID); err != nil {
+			return err
+		}
+		if task.Reward > 0 {
+			_, err = s.ledger.Credit(ctx, tx, ledger.Entry{
+				UserID:     userID,
+				Amount:     task.Reward,
+				Reason:     ledger.ReasonTaskReward,
+				SourceType: ledger.SourceTask,
+				SourceID:   int64(task.ID),
+			})
+			if err != nil {
+				return err
+			}
+		}
+
+		now := time.Now()
+		userTask.ClaimedAt = &now
+		response = buildResponse(*task, *userTask, state)
+		return nil
+	})
+	switch {
+	case errors.Is(err, ErrAlreadyClaimed):
+		return c.JSON(http.StatusConflict, map[string]string{"error": err.Error()})
+	case errors.Is(err, ErrNotCompleted):
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	case err != nil:
+		loger.Logger.Error("Ошибка при получении награды за задание",
+			zap.Error(err),
+			zap.Int("task_id", task.ID),
+			zap.Int64("user_id", telegramUser.ID))
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Ошибка при получении награды за задание"})
+	}
+	return c.JSON(http.StatusOK, response)
+}
+
+func (s *TasksService) CreateTask(c echo.Context) error {
+	var req CreateTaskRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Неверный формат запроса"})
+	}
+	if req.Title == "" || !taskTypes[req.Type] || req.Reward < 0 {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Неверные параметры задания"})
+	}
+	if req.Type == TypeJoinChannel && (req.Channel == nil || *req.Channel == "") {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Для задания join_channel нужен канал"})
+	}
+	if req.Target <= 0 {
+		req.Target = 1
+	}
+
+	task, err := s.repo.CreateTask(c.Request().Context(), req)
+	if err != nil {
+		loger.Logger.Error("Ошибка при создании задания", zap.Error(err))
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Ошибка при создании задания"})
+	}
+	return c.JSON(http.StatusCreated, task)
+}
+
+func (s *TasksService) DeleteTask(c echo.Context) error {
+	taskID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Неверный ID задания"})
+	}
+	found, err := s.repo.DeactivateTask(c.Request().Context(), taskID)
+	if err != nil {
+		loger.Logger.Error("Ошибка при удалении задания",
+			zap.Error(err),
+			zap.Int("task_id", taskID))
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Ошибка при удалении задания"})
+	}
+	if !found {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "Задание не найдено"})
+	}
+	return c.NoContent(http.StatusNoContent)
+}
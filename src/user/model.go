@@ -11,14 +11,23 @@ type UserRepo struct {
 	Energy       int    `json:"energy"`
 	MaxEnergy    int    `json:"max_energy"`
 	ProfitPerHour int    `json:"profit_per_hour"`
-	Head         *string `json:"head"`
-	Body         *string `json:"body"`
-	Legs         *string `json:"legs"`
-	Foot         *string `json:"foot"`
-	Hand         *string `json:"hand"`
 	ProfitForTap int    `json:"profit_for_tap"`
 	LastRestoration time.Time `json:"last_restoration"`
 	LastProfitPerHour time.Time `json:"last_profit_per_hour"`
+	// ProfitRemainder — дробная часть пассивного дохода в монето-миллисекундах в час
+	ProfitRemainder int64 `json:"-"`
+	XP int64 `json:"xp"`
+	// Gems — премиальная валюта
+	Gems int64 `json:"gems"`
+	// TapBoostMultiplier действует на прибыль за тап до TapBoostUntil
+	TapBoostMultiplier int `json:"-"`
+	TapBoostUntil *time.Time `json:"-"`
+	// RegenBoostMultiplier ускоряет восстановление энергии с RegenBoostStartedAt до RegenBoostUntil
+	RegenBoostMultiplier int `json:"-"`
+	RegenBoostStartedAt *time.Time `json:"-"`
+	RegenBoostUntil *time.Time `json:"-"`
+	// OfflineEarnings заполняется при начислении пассивного дохода после долгого отсутствия
+	OfflineEarnings *OfflineEarningsResponse `json:"-"`
 }
 
 type UserResponse struct {
@@ -28,12 +37,44 @@ type UserResponse struct {
 	Energy       int    `json:"energy"`
 	MaxEnergy    int    `json:"max_energy"`
 	ProfitPerHour int    `json:"profit_per_hour"`
-	Head         *string `json:"head"`
-	Body         *string `json:"body"`
-	Legs         *string `json:"legs"`
-	Foot         *string `json:"foot"`
-	Hand         *string `json:"hand"`
+	// Head, Body, Legs, Foot и Hand — надетая одежда, nil если слот пуст
+	Head         *EquippedItemResponse `json:"head"`
+	Body         *EquippedItemResponse `json:"body"`
+	Legs         *EquippedItemResponse `json:"legs"`
+	Foot         *EquippedItemResponse `json:"foot"`
+	Hand         *EquippedItemResponse `json:"hand"`
 	ProfitForTap int    `json:"profit_for_tap"`
+	Gems         int64  `json:"gems"`
+	WelcomeBack  *OfflineEarningsResponse `json:"welcome_back,omitempty"`
+	// TapBoost и RegenBoost — действующие бусты, nil если буст не активен
+	TapBoost     *BoostStateResponse `json:"tap_boost"`
+	RegenBoost   *BoostStateResponse `json:"regen_boost"`
+}
+
+// EquippedItemResponse описывает надетую вещь
+type EquippedItemResponse struct {
+	ID          int    `json:"id"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	UrlImage    string `json:"url_image"`
+	Type        string `json:"type"`
+	Rarity      string `json:"rarity"`
+	PerForTap   int    `json:"per_for_tap"`
+	PlusEnergy  int    `json:"plus_energy"`
+}
+
+// BoostStateResponse описывает действующий временный буст
+type BoostStateResponse struct {
+	Multiplier       int   `json:"multiplier"`
+	RemainingSeconds int64 `json:"remaining_seconds"`
+}
+
+// OfflineEarningsResponse описывает доход, начисленный за время отсутствия пользователя
+type OfflineEarningsResponse struct {
+	Earned      int64 `json:"earned"`
+	AwaySeconds int64 `json:"away_seconds"`
+	// Capped — отсутствие длилось дольше лимита, и доход начислен только за лимит
+	Capped      bool  `json:"capped"`
 }
 
 type UserRequest struct {
@@ -54,5 +95,45 @@ type TgIDRequest struct {
 	TgID int64 `header:"tg_id"`
 }
 
+// TapBatchRequest описывает пачку тапов, накопленных клиентом.
+// Время передается в миллисекундах Unix
+type TapBatchRequest struct {
+	Count      int   `json:"count"`
+	StartedAt  int64 `json:"started_at"`
+	FinishedAt int64 `json:"finished_at"`
+}
 
+// TapBatchResponse описывает результат применения пачки тапов
+type TapBatchResponse struct {
+	Applied int   `json:"applied"`
+	Profit  int64 `json:"profit"`
+	Balance int64 `json:"balance"`
+	Energy  int   `json:"energy"`
+}
+
+// OutOfEnergyResponse возвращается, когда у пользователя закончилась энергия
+type OutOfEnergyResponse struct {
+	Error        string `json:"error"`
+	Code         string `json:"code"`
+	NextEnergyIn int64  `json:"next_energy_in"`
+}
 
+type LevelRepo struct {
+	Level          int   `json:"level"`
+	XPRequired     int64 `json:"xp_required"`
+	MaxEnergyBonus int   `json:"max_energy_bonus"`
+	TapProfitBonus int   `json:"tap_profit_bonus"`
+	Reward         int64 `json:"reward"`
+}
+
+// LevelProgressResponse описывает прогресс пользователя до следующего уровня
+type LevelProgressResponse struct {
+	Level int   `json:"level"`
+	XP    int64 `json:"xp"`
+	// CurrentLevelXP — опыт, с которого начинается текущий уровень
+	CurrentLevelXP int64 `json:"current_level_xp"`
+	// NextLevel — следующий уровень, nil если достигнут максимальный
+	NextLevel *LevelRepo `json:"next_level"`
+	// Progress — доля пройденного пути до следующего уровня от 0 до 1
+	Progress float64 `json:"progress"`
+}
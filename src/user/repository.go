@@ -1,10 +1,18 @@
 package user
 
 import (
+	"api/db"
 	"context"
 	"database/sql"
+	"errors"
+	"time"
 )
 
+var ErrOutOfEnergy = errors.New("энергия закончилась")
+
+// ErrTapTooFast — тап пришел раньше, чем позволяет maxTapsPerSecond с момента предыдущего
+var ErrTapTooFast = errors.New("слишком частые тапы")
+
 type UserRepository struct {
 	db *sql.DB
 }
@@ -13,44 +21,54 @@ func NewUserRepository(db *sql.DB) *UserRepository {
 	return &UserRepository{db: db}
 }
 
-func (r *UserRepository) GetUser(ctx context.Context, tg_id int64) (*UserRepo, error) {
+const userColumns = "id, tg_id, username, balance, level, energy, max_energy, profit_per_hour, profit_for_tap, last_restoration, last_profit_per_hour, profit_remainder, xp, tap_boost_multiplier, tap_boost_until, regen_boost_multiplier, regen_boost_started_at, regen_boost_until, gems"
+
+func scanUser(row *sql.Row) (*UserRepo, error) {
 	var user UserRepo
-	var head, body, legs, foot sql.NullString
-	err := r.db.QueryRowContext(ctx,
-		"SELECT id, tg_id, username, balance, level, energy, max_energy, profit_per_hour, head, body, legs, foot, profit_for_tap, last_restoration, last_profit_per_hour FROM users WHERE tg_id = $1", 
-		tg_id,
-	).Scan(
-		&user.ID, &user.TgID, &user.Username, &user.Balance, &user.Level, &user.Energy, &user.MaxEnergy, &user.ProfitPerHour, &head, &body, &legs, &foot, &user.ProfitForTap, &user.LastRestoration, &user.LastProfitPerHour,
+	var tapBoostUntil, regenBoostStartedAt, regenBoostUntil sql.NullTime
+	err := row.Scan(
+		&user.ID, &user.TgID, &user.Username, &user.Balance, &user.Level, &user.Energy, &user.MaxEnergy, &user.ProfitPerHour, &user.ProfitForTap, &user.LastRestoration, &user.LastProfitPerHour, &user.ProfitRemainder, &user.XP,
+		&user.TapBoostMultiplier, &tapBoostUntil, &user.RegenBoostMultiplier, &regenBoostStartedAt, &regenBoostUntil, &user.Gems,
 	)
 	if err != nil {
 		return nil, err
 	}
 	
-	if head.Valid {
-		user.Head = &head.String
-	}
-	if body.Valid {
-		user.Body = &body.String
+	if tapBoostUntil.Valid {
+		user.TapBoostUntil = &tapBoostUntil.Time
 	}
-	if legs.Valid {
-		user.Legs = &legs.String
+	if regenBoostStartedAt.Valid {
+		user.RegenBoostStartedAt = &regenBoostStartedAt.Time
 	}
-	if foot.Valid {
-		user.Foot = &foot.String
+	if regenBoostUntil.Valid {
+		user.RegenBoostUntil = &regenBoostUntil.Time
 	}
 	
 	return &user, nil
 }
 
-func (r *UserRepository) CreateUser(ctx context.Context, tg_id int64, username string) (*UserRepo, error) {
+func (r *UserRepository) GetUser(ctx context.Context, tg_id int64) (*UserRepo, error) {
+	return scanUser(r.db.QueryRowContext(ctx, "SELECT "+userColumns+" FROM users WHERE tg_id = $1", tg_id))
+}
+
+// LockUser читает пользователя и блокирует его строку до конца транзакции
+func (r *UserRepository) LockUser(ctx context.Context, tx *sql.Tx, tg_id int64) (*UserRepo, error) {
+	return scanUser(tx.QueryRowContext(ctx, "SELECT "+userColumns+" FROM users WHERE tg_id = $1 FOR UPDATE", tg_id))
+}
+
+func (r *UserRepository) LockUserByID(ctx context.Context, tx *sql.Tx, id int) (*UserRepo, error) {
+	return scanUser(tx.QueryRowContext(ctx, "SELECT "+userColumns+" FROM users WHERE id = $1 FOR UPDATE", id))
+}
+
+func (r *UserRepository) CreateUser(ctx context.Context, tx *sql.Tx, tg_id int64, username string, referralCode string) (*UserRepo, error) {
 	var user UserRepo
-	err := r.db.QueryRowContext(ctx,
-		`INSERT INTO users (tg_id, username) 
-		VALUES ($1, $2) 
-		RETURNING id, tg_id, username, balance, level, energy, max_energy, profit_per_hour, head, body, legs, last_profit_per_hour`,
-		tg_id, username,
+	err := tx.QueryRowContext(ctx,
+		`INSERT INTO users (tg_id, username, referral_code) 
+		VALUES ($1, $2, $3) 
+		RETURNING id, tg_id, username, balance, level, energy, max_energy, profit_per_hour, last_profit_per_hour`,
+		tg_id, username, referralCode,
 	).Scan(
-		&user.ID, &user.TgID, &user.Username, &user.Balance, &user.Level, &user.Energy, &user.MaxEnergy, &user.ProfitPerHour, &user.Head, &user.Body, &user.Legs, &user.LastProfitPerHour,
+		&user.ID, &user.TgID, &user.Username, &user.Balance, &user.Level, &user.Energy, &user.MaxEnergy, &user.ProfitPerHour, &user.LastProfitPerHour,
 	)
 	if err != nil {
 		return nil, err
@@ -58,29 +76,164 @@ func (r *UserRepository) CreateUser(ctx context.Context, tg_id int64, username s
 	return &user, nil
 }
 
-func (r *UserRepository) SelectProfitForTap(ctx context.Context, tg_id int64) (int, error) {
-	var profit int
-	err := r.db.QueryRowContext(ctx, "SELECT profit_for_tap FROM users WHERE tg_id = $1", tg_id).Scan(&profit)
+// GetEquippedItems возвращает надетую пользователем одежду по слотам
+func (r *UserRepository) GetEquippedItems(ctx context.Context, id int) (map[string]*EquippedItemResponse, error) {
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT e.slot, c.id, c.name, c.description, c.url_image, c.type, c.rarity, c.per_for_tap, c.plus_energy
+		FROM equipped_items e
+		JOIN clothes c ON c.id = e.clothes_id
+		WHERE e.user_id = $1`,
+		id,
+	)
 	if err != nil {
-		return 0, err
+		return nil, err
+	}
+	defer rows.Close()
+	items := map[string]*EquippedItemResponse{}
+	for rows.Next() {
+		var slot string
+		var item EquippedItemResponse
+		if err := rows.Scan(&slot, &item.ID, &item.Name, &item.Description, &item.UrlImage, &item.Type, &item.Rarity, &item.PerForTap, &item.PlusEnergy); err != nil {
+			return nil, err
+		}
+		items[slot] = &item
+	}
+	return items, rows.Err()
+}
+
+// WithTx выполняет fn в одной транзакции базы данных
+func (r *UserRepository) WithTx(ctx context.Context, fn func(tx *sql.Tx) error) error {
+	return db.WithTx(ctx, r.db, fn)
+}
+
+// SpendEnergyForTap списывает единицу энергии за тап и возвращает id пользователя и прибыль за тап —
+// не меньше minProfit с учетом действующего буста тапов.
+// Как и пачка тапов, тап ограничивается скоростью maxTapsPerSecond с момента предыдущего тапа.
+// Возвращает ErrOutOfEnergy, если энергии нет, и ErrTapTooFast, если тап пришел слишком рано
+func (r *UserRepository) SpendEnergyForTap(ctx context.Context, tx *sql.Tx, tg_id int64, maxTapsPerSecond int, minProfit int) (int, int, error) {
+	var id, energy, profit int
+	var ready bool
+	err := tx.QueryRowContext(ctx,
+		`SELECT id, energy, last_tap_at IS NULL OR last_tap_at <= NOW() - INTERVAL '1 second' / $2,
+			GREATEST(profit_for_tap, $3) * CASE WHEN tap_boost_until > NOW() THEN tap_boost_multiplier ELSE 1 END
+		FROM users WHERE tg_id = $1 FOR UPDATE`,
+		tg_id, maxTapsPerSecond, minProfit,
+	).Scan(&id, &energy, &ready, &profit)
+	if err != nil {
+		return 0, 0, err
+	}
+	if energy <= 0 {
+		return 0, 0, ErrOutOfEnergy
+	}
+	if !ready {
+		return 0, 0, ErrTapTooFast
 	}
-	if profit <= 0 {
-		return 1, nil
+	if _, err := tx.ExecContext(ctx, "UPDATE users SET energy = energy - 1, last_tap_at = NOW() WHERE id = $1", id); err != nil {
+		return 0, 0, err
 	}
-	return profit, nil
+	return id, profit, nil
 }
 
-func (r *UserRepository) UpdateBalanceForTap(ctx context.Context, tg_id int64, balance int) error {
-	_, err := r.db.ExecContext(ctx, "UPDATE users SET balance = balance + $1, energy = energy - 1 WHERE tg_id = $2", balance, tg_id)
+// UpdateAccrual сохраняет результат начисления пассивного дохода и восстановления энергии.
+// Если энергия восстановлена полностью, отсчет следующего интервала начинается заново,
+// иначе время последнего восстановления сдвигается ровно на использованные интервалы
+func (r *UserRepository) UpdateAccrual(ctx context.Context, tx *sql.Tx, id int, energy int, energyFull bool, regenConsumed time.Duration, remainder int64) error {
+	_, err := tx.ExecContext(ctx,
+		`UPDATE users SET
+			energy = LEAST(max_energy, energy + $1),
+			last_restoration = CASE WHEN $2 THEN NOW() ELSE last_restoration + $3 * INTERVAL '1 millisecond' END,
+			last_profit_per_hour = NOW(),
+			profit_remainder = $4
+		WHERE id = $5`,
+		energy, energyFull, regenConsumed.Milliseconds(), remainder, id,
+	)
 	return err
 }
 
-func (r *UserRepository) UpdateEnergy(ctx context.Context, tg_id int64, energy int) error {
-	_, err := r.db.ExecContext(ctx, "UPDATE users SET energy = energy + $1, last_restoration = NOW() WHERE tg_id = $2", energy, tg_id)
+// UpdateProfitAccrual сохраняет время, до которого начислен пассивный доход, и остаток
+func (r *UserRepository) UpdateProfitAccrual(ctx context.Context, tx *sql.Tx, id int, accruedUntil time.Time, remainder int64) error {
+	_, err := tx.ExecContext(ctx,
+		"UPDATE users SET last_profit_per_hour = $1, profit_remainder = $2 WHERE id = $3",
+		accruedUntil, remainder, id,
+	)
 	return err
 }
 
-func (r *UserRepository) UpdateBalanceForProfitPerHour(ctx context.Context, tg_id int64, balance int) error {
-	_, err := r.db.ExecContext(ctx, "UPDATE users SET balance = $1, last_profit_per_hour = NOW() WHERE tg_id = $2", balance, tg_id)
+// SpendEnergyForTapBatch списывает энергию за пачку тапов одним запросом и возвращает id пользователя.
+// Количество тапов ограничивается доступной энергией и скоростью maxTapsPerSecond с момента предыдущего тапа или пачки,
+// прибыль за тап — не меньше minProfit с учетом действующего буста тапов.
+// Баланс не меняется: начисление выполняется через журнал операций
+func (r *UserRepository) SpendEnergyForTapBatch(ctx context.Context, tx *sql.Tx, tg_id int64, count int, maxTapsPerSecond int, minProfit int) (int, *TapBatchResponse, error) {
+	var id int
+	var result TapBatchResponse
+	err := tx.QueryRowContext(ctx,
+		`WITH u AS (
+			SELECT id,
+				LEAST($1::bigint, energy, CEIL(EXTRACT(EPOCH FROM NOW() - COALESCE(last_tap_at, NOW() - INTERVAL '1 hour')) * $2)::bigint) AS taps,
+				GREATEST(profit_for_tap, $4) * CASE WHEN tap_boost_until > NOW() THEN tap_boost_multiplier ELSE 1 END AS profit
+			FROM users WHERE tg_id = $3 FOR UPDATE
+		)
+		UPDATE users SET energy = users.energy - u.taps, last_tap_at = NOW()
+		FROM u WHERE users.id = u.id
+		RETURNING users.id, u.taps, u.taps * u.profit, users.energy`,
+		count, maxTapsPerSecond, tg_id, minProfit,
+	).Scan(&id, &result.Applied, &result.Profit, &result.Energy)
+	if err != nil {
+		return 0, nil, err
+	}
+	return id, &result, nil
+}
+
+// AddXP добавляет опыт пользователю и возвращает новый опыт и текущий уровень
+func (r *UserRepository) AddXP(ctx context.Context, tx *sql.Tx, id int, xp int64) (int64, int, error) {
+	var total int64
+	var level int
+	err := tx.QueryRowContext(ctx, "UPDATE users SET xp = xp + $1 WHERE id = $2 RETURNING xp, level", xp, id).Scan(&total, &level)
+	if err != nil {
+		return 0, 0, err
+	}
+	return total, level, nil
+}
+
+// GetReachedLevels возвращает уровни выше level, опыт для которых уже набран, по возрастанию
+func (r *UserRepository) GetReachedLevels(ctx context.Context, tx *sql.Tx, level int, xp int64) ([]LevelRepo, error) {
+	rows, err := tx.QueryContext(ctx,
+		"SELECT level, xp_required, max_energy_bonus, tap_profit_bonus, reward FROM levels WHERE level > $1 AND xp_required <= $2 ORDER BY level",
+		level, xp,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	levels := []LevelRepo{}
+	for rows.Next() {
+		var l LevelRepo
+		if err := rows.Scan(&l.Level, &l.XPRequired, &l.MaxEnergyBonus, &l.TapProfitBonus, &l.Reward); err != nil {
+			return nil, err
+		}
+		levels = append(levels, l)
+	}
+	return levels, rows.Err()
+}
+
+// ApplyLevelUp переводит пользователя на уровень и выдает бонусы уровня
+func (r *UserRepository) ApplyLevelUp(ctx context.Context, tx *sql.Tx, id int, level LevelRepo) error {
+	_, err := tx.ExecContext(ctx,
+		"UPDATE users SET level = $1, max_energy = max_energy + $2, profit_for_tap = profit_for_tap + $3 WHERE id = $4",
+		level.Level, level.MaxEnergyBonus, level.TapProfitBonus, id,
+	)
 	return err
-}
\ No newline at end of file
+}
+
+// GetLevel возвращает описание уровня. Для отсутствующего уровня возвращает sql.ErrNoRows
+func (r *UserRepository) GetLevel(ctx context.Context, level int) (*LevelRepo, error) {
+	var l LevelRepo
+	err := r.db.QueryRowContext(ctx,
+		"SELECT level, xp_required, max_energy_bonus, tap_profit_bonus, reward FROM levels WHERE level = $1",
+		level,
+	).Scan(&l.Level, &l.XPRequired, &l.MaxEnergyBonus, &l.TapProfitBonus, &l.Reward)
+	if err != nil {
+		return nil, err
+	}
+	return &l, nil
+}
@@ -1,7 +1,6 @@
 package user
 
 import (
-	"database/sql"
 	"github.com/labstack/echo/v4"
 	"api/src/middleware"
 	"api/src/core/config"
@@ -31,6 +30,9 @@ func (h *UserHandler) RegisterRoutes(e *echo.Echo) {
 	userGroup.GET("", h.GetUser)
 	userGroup.POST("", h.CreateUser)
 	userGroup.POST("/tap", h.TapUser)
+	userGroup.POST("/tap/batch", h.TapBatch)
+	userGroup.GET("/transactions", h.GetTransactions)
+	userGroup.GET("/level", h.GetLevel)
 }
 
 // @Summary Получить пользователя
@@ -65,6 +67,8 @@ func (h *UserHandler) CreateUser(c echo.Context) error {
 // @Accept json
 // @Produce json
 // @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} OutOfEnergyResponse
+// @Failure 429 {object} map[string]string
 // @Failure 500 {object} map[string]string
 // @Router /user/tap [post]
 // @Security TelegramAuth
@@ -72,9 +76,51 @@ func (h *UserHandler) TapUser(c echo.Context) error {
 	return h.service.TapUserHandler(c)
 }
 
-func SetupUser(e *echo.Echo, db *sql.DB, config *config.Config) {
-	repo := NewUserRepository(db)
-	service := NewService(repo)
+// @Summary Пачка тапов пользователя
+// @Description Применяет накопленные клиентом тапы одним запросом. Количество ограничивается энергией и допустимой частотой тапов
+// @Tags user
+// @Accept json
+// @Produce json
+// @Param request body TapBatchRequest true "Количество тапов и время начала/конца пачки в миллисекундах"
+// @Success 200 {object} TapBatchResponse
+// @Failure 400 {object} OutOfEnergyResponse
+// @Failure 500 {object} map[string]string
+// @Router /user/tap/batch [post]
+// @Security TelegramAuth
+func (h *UserHandler) TapBatch(c echo.Context) error {
+	return h.service.TapBatchHandler(c)
+}
+
+// @Summary История операций
+// @Description Возвращает изменения баланса пользователя от новых к старым с пагинацией по курсору
+// @Tags user
+// @Accept json
+// @Produce json
+// @Param cursor query int false "next_cursor из предыдущего ответа"
+// @Param limit query int false "Размер страницы (по умолчанию 50, максимум 100)"
+// @Success 200 {object} ledger.TransactionsResponse
+// @Failure 400 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /user/transactions [get]
+// @Security TelegramAuth
+func (h *UserHandler) GetTransactions(c echo.Context) error {
+	return h.service.GetTransactionsHandler(c)
+}
+
+// @Summary Уровень пользователя
+// @Description Возвращает текущий уровень, опыт и прогресс до следующего уровня
+// @Tags user
+// @Accept json
+// @Produce json
+// @Success 200 {object} LevelProgressResponse
+// @Failure 500 {object} map[string]string
+// @Router /user/level [get]
+// @Security TelegramAuth
+func (h *UserHandler) GetLevel(c echo.Context) error {
+	return h.service.GetLevelHandler(c)
+}
+
+func SetupUser(e *echo.Echo, service UserService, config *config.Config) {
 	handler := NewUserHandler(service, config)
 	handler.RegisterRoutes(e)
 }
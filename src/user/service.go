@@ -2,164 +2,387 @@ package user
 
 import (
 	"context"
+	"database/sql"
 	"github.com/labstack/echo/v4"
 	"api/src/middleware"
+	"api/src/ledger"
+	"api/src/referral"
+	"api/src/core/economy"
+	"api/src/core/events"
 	"api/src/core/loger"
+	"strconv"
 	"time"
-	"sync"
 	"go.uber.org/zap"
 )
 
 type UserService interface {
 	GetUser(ctx context.Context, tg_id int64) (*UserRepo, error)
-	CreateUser(ctx context.Context, tg_id int64, username string) (*UserRepo, error)
-	SelectProfitForTap(ctx context.Context, tg_id int64) (int, error)
-	UpdateBalanceForTap(ctx context.Context, tg_id int64, balance int) error
-	UpdateEnergy(ctx context.Context, tg_id int64, energy int) error
-	UpdateBalanceForProfitPerHour(ctx context.Context, tg_id int64, balance int) error
-	AddProfitPerHour(ctx context.Context, u *UserRepo) (int64, error)
-	EnergyRestoration(ctx context.Context, u *UserRepo) (int64, error)
-	AddProfitPerHourAndEnergyRestoration(ctx context.Context, u *UserRepo) (profit int64, energy int64, err error)
-	ReturnUser(u *UserRepo) (*UserResponse, error)
+	CreateUser(ctx context.Context, tg_id int64, username string, startParam string) (*UserRepo, error)
+	UpdateBalanceForTap(ctx context.Context, tg_id int64) (int, error)
+	AccrueUser(ctx context.Context, tg_id int64) (*UserRepo, error)
+	AccrueUntil(ctx context.Context, tx *sql.Tx, userID int, until time.Time) error
+	AddXP(ctx context.Context, tx *sql.Tx, userID int, xp int64) error
+	ReturnUser(ctx context.Context, u *UserRepo) (*UserResponse, error)
 	GetUserHandler(c echo.Context) error
 	CreateUserHandler(c echo.Context) error
 	TapUserHandler(c echo.Context) error
+	TapBatchHandler(c echo.Context) error
+	GetLevelHandler(c echo.Context) error
+	GetTransactionsHandler(c echo.Context) error
 }
 
+const (
+	// maxTapsPerSecond — физически достижимая частота тапов одного пользователя
+	maxTapsPerSecond = 20
+	// maxClockSkew — допустимое опережение часов клиента
+	maxClockSkew = 5 * time.Second
+	// codeOutOfEnergy — код ошибки для клиента, когда энергия закончилась
+	codeOutOfEnergy = "out_of_energy"
+	// welcomeBackMinAway — после какого отсутствия клиенту показывается доход за время отсутствия
+	welcomeBackMinAway = 5 * time.Minute
+)
+
 type Service struct {
 	repo *UserRepository
+	ledger *ledger.LedgerService
+	referral *referral.ReferralService
 }
 
-func NewService(repo *UserRepository) UserService {
-	return &Service{repo: repo}
+func NewService(repo *UserRepository, ledger *ledger.LedgerService, referral *referral.ReferralService) UserService {
+	return &Service{repo: repo, ledger: ledger, referral: referral}
 }
 
 func (s *Service) GetUser(ctx context.Context, tg_id int64) (*UserRepo, error) {
 	return s.repo.GetUser(ctx, tg_id)
 }
 
-func (s *Service) CreateUser(ctx context.Context, tg_id int64, username string) (*UserRepo, error) {
-	return s.repo.CreateUser(ctx, tg_id, username)
+// CreateUser создает пользователя с реферальным кодом и, если он пришел по приглашению,
+// связывает его с пригласившим и выдает бонусы
+func (s *Service) CreateUser(ctx context.Context, tg_id int64, username string, startParam string) (*UserRepo, error) {
+	code, err := referral.NewCode()
+	if err != nil {
+		return nil, err
+	}
+	var user *UserRepo
+	err = s.repo.WithTx(ctx, func(tx *sql.Tx) error {
+		created, err := s.repo.CreateUser(ctx, tx, tg_id, username, code)
+		if err != nil {
+			return err
+		}
+		if err := s.referral.Attach(ctx, tx, created.ID, startParam); err != nil {
+			return err
+		}
+		user, err = s.repo.LockUser(ctx, tx, tg_id)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return user, nil
 }
 
-func (s *Service) SelectProfitForTap(ctx context.Context, tg_id int64) (int, error) {
-	return s.repo.SelectProfitForTap(ctx, tg_id)
+// UpdateBalanceForTap списывает единицу энергии и начисляет прибыль за тап через журнал операций.
+// Прибыль считается в той же транзакции с учетом минимальной прибыли и буста тапов и возвращается.
+// Возвращает ErrOutOfEnergy, если энергии нет, и ErrTapTooFast, если тапы чаще maxTapsPerSecond
+func (s *Service) UpdateBalanceForTap(ctx context.Context, tg_id int64) (int, error) {
+	var profit int
+	err := s.repo.WithTx(ctx, func(tx *sql.Tx) error {
+		rules := economy.Get()
+		userID, tapProfit, err := s.repo.SpendEnergyForTap(ctx, tx, tg_id, maxTapsPerSecond, rules.TapMinProfit)
+		if err != nil {
+			return err
+		}
+		if err := s.AddXP(ctx, tx, userID, int64(rules.XPPerTap)); err != nil {
+			return err
+		}
+		if err := events.Publish(ctx, tx, events.Event{Type: events.TapsMade, UserID: userID, Amount: 1}); err != nil {
+			return err
+		}
+		_, err = s.ledger.Credit(ctx, tx, ledger.Entry{
+			UserID: userID,
+			Amount: int64(tapProfit),
+			Reason: ledger.ReasonTap,
+		})
+		profit = tapProfit
+		return err
+	})
+	return profit, err
 }
 
-func (s *Service) UpdateBalanceForTap(ctx context.Context, tg_id int64, balance int) error {
-	return s.repo.UpdateBalanceForTap(ctx, tg_id, balance)
+// accrueProfit рассчитывает пассивный доход за elapsed с точностью до миллисекунды.
+// Дробная часть монеты не теряется, а возвращается как остаток для следующего начисления
+func accrueProfit(profitPerHour int, remainder int64, elapsed time.Duration) (profit int64, newRemainder int64) {
+	units := int64(profitPerHour)*elapsed.Milliseconds() + remainder
+	msPerHour := time.Hour.Milliseconds()
+	return units / msPerHour, units % msPerHour
 }
 
-func (s *Service) UpdateEnergy(ctx context.Context, tg_id int64, energy int) error {
-	return s.repo.UpdateEnergy(ctx, tg_id, energy)
+// UnclaimedProfit возвращает пассивный доход, накопленный с lastProfitPerHour, но еще не начисленный,
+// и начало периода, за который он набежал, с учетом лимита офлайн-дохода
+func UnclaimedProfit(profitPerHour int, remainder int64, lastProfitPerHour time.Time, now time.Time) (int64, time.Time) {
+	start := lastProfitPerHour
+	if incomeCap := economy.Get().OfflineIncomeCap(); incomeCap > 0 && now.Sub(start) > incomeCap {
+		start = now.Add(-incomeCap)
+	}
+	if !now.After(start) {
+		return 0, start
+	}
+	profit, _ := accrueProfit(profitPerHour, remainder, now.Sub(start))
+	return profit, start
 }
 
-func (s *Service) UpdateBalanceForProfitPerHour(ctx context.Context, tg_id int64, balance int) error {
-	return s.repo.UpdateBalanceForProfitPerHour(ctx, tg_id, balance)
+// restoreEnergy рассчитывает, сколько энергии восстановилось за elapsed.
+// consumed — время целых использованных интервалов, full — энергия восстановлена до максимума
+func restoreEnergy(energy int, maxEnergy int, elapsed time.Duration, rules *economy.Rules) (restored int, consumed time.Duration, full bool) {
+	need := maxEnergy - energy
+	if need <= 0 {
+		return 0, 0, true
+	}
+	if elapsed <= 0 {
+		return 0, 0, false
+	}
+	intervals := elapsed / rules.EnergyRegenInterval()
+	consumed = intervals * rules.EnergyRegenInterval()
+	restored64 := int64(intervals) * int64(rules.EnergyRegenAmount)
+	if restored64 >= int64(need) {
+		return need, consumed, true
+	}
+	return int(restored64), consumed, false
 }
 
-func (s *Service) AddProfitPerHour(ctx context.Context, u *UserRepo) (int64, error) {
-	timeDifference := time.Since(u.LastProfitPerHour)
-	minutes := int64(timeDifference.Minutes())
-	intervals := minutes 
-	if intervals == 0 {
-		return u.Balance, nil
+// regenBoostOverlap возвращает, какая часть отрезка [from, to] пришлась на буст восстановления энергии
+func regenBoostOverlap(u *UserRepo, from time.Time, to time.Time) time.Duration {
+	if u.RegenBoostStartedAt == nil || u.RegenBoostUntil == nil || u.RegenBoostMultiplier <= 1 {
+		return 0
 	}
-	
-	// Рассчитываем прибыль только за прошедшее время
-	profit := ((int64(u.ProfitPerHour) / 60) * intervals)
-	newBalance := u.Balance + profit
-	
-	// Обновляем баланс и время последнего начисления
-	err := s.repo.UpdateBalanceForProfitPerHour(ctx, u.TgID, int(newBalance))
-	if err != nil {
-		loger.Logger.Error("Ошибка при обновлении баланса",
-			zap.Error(err))
-		return 0, err
+	start, end := from, to
+	if u.RegenBoostStartedAt.After(start) {
+		start = *u.RegenBoostStartedAt
 	}
-	
-	// Обновляем время последнего начисления в объекте
-	u.LastProfitPerHour = time.Now()
-	u.Balance = newBalance
-	
-	loger.Logger.Info("Обновление баланса",
-		zap.Int64("old_balance", u.Balance - profit),
-		zap.Int64("profit", profit),
-		zap.Int64("new_balance", newBalance),
-		zap.Int64("minutes_passed", minutes),
-		zap.Int("profit_per_hour", u.ProfitPerHour))
-	
-	return newBalance, nil
+	if u.RegenBoostUntil.Before(end) {
+		end = *u.RegenBoostUntil
+	}
+	if end.Before(start) {
+		return 0
+	}
+	return end.Sub(start)
 }
 
-func (s *Service) EnergyRestoration(ctx context.Context, u *UserRepo) (int64, error) {
-	timeDifference := time.Since(u.LastRestoration)
-	minutes := int64(timeDifference.Minutes())
-	intervals := minutes / 2
-	needEnergy := int64(u.MaxEnergy - u.Energy)
-	
-	loger.Logger.Info("Восстановление энергии",
-		zap.Int64("intervals", intervals),
-		zap.Int64("need_energy", needEnergy),
-		zap.Int("current_energy", u.Energy))
-		
-	if intervals == 0 {
-		return int64(u.Energy), nil
-	}
-	if intervals > needEnergy {
-		intervals = needEnergy
-	}
-	err := s.repo.UpdateEnergy(ctx, u.TgID, int(intervals))
-	if err != nil {
-		loger.Logger.Error("Ошибка при обновлении энергии",
-			zap.Error(err))
-		return 0, err
+// regenElapsed возвращает время восстановления энергии с from до to.
+// Под бустом каждая секунда засчитывается за multiplier секунд
+func regenElapsed(u *UserRepo, from time.Time, to time.Time) time.Duration {
+	if to.Before(from) {
+		return 0
 	}
-	
-	// Обновляем время последнего восстановления в объекте
-	u.LastRestoration = time.Now()
-	u.Energy += int(intervals)
-	
-	return int64(u.Energy), nil
+	return to.Sub(from) + regenBoostOverlap(u, from, to)*time.Duration(u.RegenBoostMultiplier-1)
 }
 
-func (s *Service) AddProfitPerHourAndEnergyRestoration(ctx context.Context, u *UserRepo) (profit int64, energy int64, err error) {
-	type result struct {
-		value int64
-		err   error
+// regenRealTime переводит время восстановления effective, отсчитанное от from, в реальное время
+func regenRealTime(u *UserRepo, from time.Time, effective time.Duration) time.Duration {
+	if u.RegenBoostStartedAt == nil || u.RegenBoostUntil == nil || u.RegenBoostMultiplier <= 1 {
+		return effective
+	}
+	multiplier := time.Duration(u.RegenBoostMultiplier)
+	var real time.Duration
+	// До начала буста время идет как обычно
+	if before := u.RegenBoostStartedAt.Sub(from); before > 0 {
+		if effective <= before {
+			return effective
+		}
+		real, effective = before, effective-before
+	}
+	boosted := u.RegenBoostUntil.Sub(from.Add(real))
+	if boosted < 0 {
+		boosted = 0
+	}
+	if effective <= boosted*multiplier {
+		return real + effective/multiplier
 	}
+	return real + boosted + effective - boosted*multiplier
+}
+
+// boostState возвращает состояние буста, действующего до until, или nil, если он закончился
+func boostState(multiplier int, until *time.Time, now time.Time) *BoostStateResponse {
+	if until == nil || !until.After(now) {
+		return nil
+	}
+	return &BoostStateResponse{
+		Multiplier:       multiplier,
+		RemainingSeconds: int64((until.Sub(now) + time.Second - 1) / time.Second),
+	}
+}
 
-	profitChan := make(chan result)
-	energyChan := make(chan result)
+// AccrueUser начисляет пассивный доход и восстанавливает энергию в одной транзакции
+// под блокировкой строки пользователя. Расчет идет по свежей заблокированной строке,
+// а баланс меняется относительно через журнал, поэтому параллельные тапы и покупки не теряются
+func (s *Service) AccrueUser(ctx context.Context, tg_id int64) (*UserRepo, error) {
+	rules := economy.Get()
+	var user *UserRepo
+	err := s.repo.WithTx(ctx, func(tx *sql.Tx) error {
+		u, err := s.repo.LockUser(ctx, tx, tg_id)
+		if err != nil {
+			return err
+		}
+		// Обработчики могут изменить доход в час, поэтому пользователь перечитывается
+		if err := events.Publish(ctx, tx, events.Event{Type: events.AccrualStarted, UserID: u.ID}); err != nil {
+			return err
+		}
+		u, err = s.repo.LockUser(ctx, tx, tg_id)
+		if err != nil {
+			return err
+		}
+		now := time.Now()
+
+		away := now.Sub(u.LastProfitPerHour)
+		if away < 0 {
+			away = 0
+		}
+		elapsed := away
+		capped := false
+		if incomeCap := rules.OfflineIncomeCap(); incomeCap > 0 && elapsed > incomeCap {
+			elapsed = incomeCap
+			capped = true
+		}
+		// Рассчитываем прибыль только за прошедшее время, с учетом остатка от прошлого начисления
+		profit, remainder := accrueProfit(u.ProfitPerHour, u.ProfitRemainder, elapsed)
+		// Энергия восстанавливается с учетом буста, а время последнего восстановления сдвигается на реальное время
+		restored, consumed, full := restoreEnergy(u.Energy, u.MaxEnergy, regenElapsed(u, u.LastRestoration, now), rules)
+		consumed = regenRealTime(u, u.LastRestoration, consumed)
+
+		balance, err := s.ledger.Credit(ctx, tx, ledger.Entry{
+			UserID: u.ID,
+			Amount: profit,
+			Reason: ledger.ReasonProfitPerHour,
+		})
+		if err != nil {
+			return err
+		}
+		if err := s.referral.ShareIncome(ctx, tx, u.ID, profit); err != nil {
+			return err
+		}
+		if err := s.repo.UpdateAccrual(ctx, tx, u.ID, restored, full, consumed, remainder); err != nil {
+			return err
+		}
 
-	go func() {
-		profit, err := s.AddProfitPerHour(ctx, u)
-		profitChan <- result{profit, err}
-	}()
+		loger.Logger.Info("Начисление пассивного дохода и энергии",
+			zap.Int64("user_id", tg_id),
+			zap.Int64("profit", profit),
+			zap.Int64("new_balance", balance),
+			zap.Duration("elapsed", elapsed),
+			zap.Bool("capped", capped),
+			zap.Int("profit_per_hour", u.ProfitPerHour),
+			zap.Int("restored_energy", restored))
 
-	go func() {
-		energy, err := s.EnergyRestoration(ctx, u)
-		energyChan <- result{energy, err}
-	}()
+		u.Balance = balance
+		u.ProfitRemainder = remainder
+		u.LastProfitPerHour = now
+		u.Energy += restored
+		if full {
+			u.LastRestoration = now
+		} else {
+			u.LastRestoration = u.LastRestoration.Add(consumed)
+		}
+		if profit > 0 && away >= welcomeBackMinAway {
+			u.OfflineEarnings = &OfflineEarningsResponse{
+				Earned:      profit,
+				AwaySeconds: int64(away.Seconds()),
+				Capped:      capped,
+			}
+		}
+		user = u
+		return nil
+	})
+	if err != nil {
+		loger.Logger.Error("Ошибка при начислении пассивного дохода и энергии",
+			zap.Error(err),
+			zap.Int64("user_id", tg_id))
+		return nil, err
+	}
+	return user, nil
+}
 
-	profitResult := <-profitChan
-	if profitResult.err != nil {
-		loger.Logger.Error("Ошибка при добавлении прибыли",
-			zap.Error(profitResult.err))
-		return 0, 0, profitResult.err
+// AccrueUntil начисляет пассивный доход по текущей ставке до момента until в транзакции tx.
+// Нужен перед изменением дохода в час задним числом, например при завершении отложенного улучшения.
+// Энергия не восстанавливается
+func (s *Service) AccrueUntil(ctx context.Context, tx *sql.Tx, userID int, until time.Time) error {
+	u, err := s.repo.LockUserByID(ctx, tx, userID)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	if until.After(now) {
+		until = now
 	}
+	// Лимит офлайн-дохода отсчитывается от текущего момента, как и при обычном начислении
+	start := u.LastProfitPerHour
+	if incomeCap := economy.Get().OfflineIncomeCap(); incomeCap > 0 && now.Sub(start) > incomeCap {
+		start = now.Add(-incomeCap)
+	}
+	if !until.After(start) {
+		return nil
+	}
+	profit, remainder := accrueProfit(u.ProfitPerHour, u.ProfitRemainder, until.Sub(start))
 
-	energyResult := <-energyChan
-	if energyResult.err != nil {
-		loger.Logger.Error("Ошибка при восстановлении энергии",
-			zap.Error(energyResult.err))
-		return 0, 0, energyResult.err
+	_, err = s.ledger.Credit(ctx, tx, ledger.Entry{
+		UserID: u.ID,
+		Amount: profit,
+		Reason: ledger.ReasonProfitPerHour,
+	})
+	if err != nil {
+		return err
+	}
+	if err := s.referral.ShareIncome(ctx, tx, u.ID, profit); err != nil {
+		return err
 	}
+	return s.repo.UpdateProfitAccrual(ctx, tx, u.ID, until, remainder)
+}
 
-	return profitResult.value, energyResult.value, nil
+// AddXP начисляет опыт в транзакции tx и повышает уровень, если набран порог.
+// За каждый новый уровень выдаются его бонусы и награда
+func (s *Service) AddXP(ctx context.Context, tx *sql.Tx, userID int, xp int64) error {
+	if xp <= 0 {
+		return nil
+	}
+	total, level, err := s.repo.AddXP(ctx, tx, userID, xp)
+	if err != nil {
+		return err
+	}
+	reached, err := s.repo.GetReachedLevels(ctx, tx, level, total)
+	if err != nil {
+		return err
+	}
+	for _, l := range reached {
+		if err := s.repo.ApplyLevelUp(ctx, tx, userID, l); err != nil {
+			return err
+		}
+		_, err := s.ledger.Credit(ctx, tx, ledger.Entry{
+			UserID:     userID,
+			Amount:     l.Reward,
+			Reason:     ledger.ReasonLevelUp,
+			SourceType: ledger.SourceLevel,
+			SourceID:   int64(l.Level),
+		})
+		if err != nil {
+			return err
+		}
+		err = events.Publish(ctx, tx, events.Event{Type: events.LevelReached, UserID: userID, Amount: int64(l.Level)})
+		if err != nil {
+			return err
+		}
+		loger.Logger.Info("Повышение уровня",
+			zap.Int("user_id", userID),
+			zap.Int("level", l.Level),
+			zap.Int64("xp", total))
+	}
+	return nil
 }
 
-func (s *Service) ReturnUser(u *UserRepo) (*UserResponse, error) {
+// ReturnUser собирает ответ клиенту вместе с надетой одеждой
+func (s *Service) ReturnUser(ctx context.Context, u *UserRepo) (*UserResponse, error) {
+	equipped, err := s.repo.GetEquippedItems(ctx, u.ID)
+	if err != nil {
+		return nil, err
+	}
 	user := &UserResponse{
 		Username: u.Username,
 		Balance: u.Balance,
@@ -167,11 +390,16 @@ func (s *Service) ReturnUser(u *UserRepo) (*UserResponse, error) {
 		Energy: u.Energy,
 		MaxEnergy: u.MaxEnergy,
 		ProfitPerHour: u.ProfitPerHour,
-		Head: u.Head,
-		Body: u.Body,
-		Legs: u.Legs,
-		Foot: u.Foot,
+		Head: equipped["head"],
+		Body: equipped["body"],
+		Legs: equipped["legs"],
+		Foot: equipped["foot"],
+		Hand: equipped["hand"],
 		ProfitForTap: u.ProfitForTap,
+		Gems: u.Gems,
+		WelcomeBack: u.OfflineEarnings,
+		TapBoost: boostState(u.TapBoostMultiplier, u.TapBoostUntil, time.Now()),
+		RegenBoost: boostState(u.RegenBoostMultiplier, u.RegenBoostUntil, time.Now()),
 	}
 	return user, nil
 }
@@ -179,49 +407,16 @@ func (s *Service) ReturnUser(u *UserRepo) (*UserResponse, error) {
 func (s *Service) GetUserHandler(c echo.Context) error {
 	ctx := c.Request().Context()
 	telegramUser := c.Get("telegram_user").(*middleware.TelegramUser)
-	
-	// Создаем канал для результатов
-	type result struct {
-		user *UserRepo
-		profit int64
-		energy int64
-		err error
+
+	user, err := s.AccrueUser(ctx, telegramUser.ID)
+	if err != nil {
+		return c.JSON(500, err.Error())
 	}
-	
-	resultChan := make(chan result)
-	
-	go func() {
-		// Получаем пользователя асинхронно
-		user, err := s.GetUser(ctx, telegramUser.ID)
-		if err != nil {
-			resultChan <- result{err: err}
-			return
-		}
-		
-		// Обновляем профит и энергию асинхронно
-		profit, energy, err := s.AddProfitPerHourAndEnergyRestoration(ctx, user)
-		resultChan <- result{
-			user: user,
-			profit: profit,
-			energy: energy,
-			err: err,
-		}
-	}()
-	
-	// Ожидаем результат с таймаутом
-	select {
-	case <-ctx.Done():
-		return c.JSON(500, "Request timeout")
-	case r := <-resultChan:
-		if r.err != nil {
-			return c.JSON(500, r.err.Error())
-		}
-		userResponse, err := s.ReturnUser(r.user)
-		if err != nil {
-			return c.JSON(500, err.Error())
-		}
-		return c.JSON(200, userResponse)
+	userResponse, err := s.ReturnUser(ctx, user)
+	if err != nil {
+		return c.JSON(500, err.Error())
 	}
+	return c.JSON(200, userResponse)
 }
 
 func (s *Service) CreateUserHandler(c echo.Context) error {
@@ -232,7 +427,8 @@ func (s *Service) CreateUserHandler(c echo.Context) error {
 		zap.Int64("user_id", telegramUser.ID),
 		zap.String("username", telegramUser.Username))
 	
-	user, err := s.repo.CreateUser(c.Request().Context(), telegramUser.ID, telegramUser.Username)
+	startParam, _ := c.Get("start_param").(string)
+	user, err := s.CreateUser(c.Request().Context(), telegramUser.ID, telegramUser.Username, startParam)
 	if err != nil {
 		loger.Logger.Error("Ошибка при создании пользователя",
 			zap.Error(err))
@@ -248,43 +444,14 @@ func (s *Service) TapUserHandler(c echo.Context) error {
 	ctx := c.Request().Context()
 	telegramUser := c.Get("telegram_user").(*middleware.TelegramUser)
 	
-	var wg sync.WaitGroup
-	errChan := make(chan error, 2)
-	profitChan := make(chan int, 1)
-	
-	// Асинхронно получаем профит за тап
-	wg.Add(1)
-	go func() {
-		defer wg.Done()
-		profit, err := s.SelectProfitForTap(ctx, telegramUser.ID)
-		if err != nil {
-			errChan <- err
-			return
+	profit, err := s.UpdateBalanceForTap(ctx, telegramUser.ID)
+	if err != nil {
+		if err == ErrOutOfEnergy {
+			return s.outOfEnergy(c, telegramUser.ID)
 		}
-		profitChan <- profit
-	}()
-	
-	// Ждем завершения горутин
-	go func() {
-		wg.Wait()
-		close(errChan)
-		close(profitChan)
-	}()
-	
-	// Проверяем ошибки
-	select {
-	case err := <-errChan:
-		if err != nil {
-			return c.JSON(500, err.Error())
+		if err == ErrTapTooFast {
+			return c.JSON(429, map[string]string{"error": err.Error()})
 		}
-	case <-ctx.Done():
-		return c.JSON(500, "Request timeout")
-	default:
-	}
-	
-	// Получаем профит и обновляем баланс
-	profit := <-profitChan
-	if err := s.UpdateBalanceForTap(ctx, telegramUser.ID, profit); err != nil {
 		return c.JSON(500, err.Error())
 	}
 	
@@ -292,3 +459,185 @@ func (s *Service) TapUserHandler(c echo.Context) error {
 		"profit": profit,
 	})
 }
+
+func (s *Service) TapBatchHandler(c echo.Context) error {
+	ctx := c.Request().Context()
+	telegramUser := c.Get("telegram_user").(*middleware.TelegramUser)
+
+	var req TapBatchRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(400, map[string]string{"error": "Неверный формат запроса"})
+	}
+	if req.Count <= 0 || req.FinishedAt < req.StartedAt {
+		return c.JSON(400, map[string]string{"error": "Неверные параметры пачки тапов"})
+	}
+	if time.UnixMilli(req.FinishedAt).After(time.Now().Add(maxClockSkew)) {
+		return c.JSON(400, map[string]string{"error": "Время пачки тапов в будущем"})
+	}
+
+	// Не больше тапов, чем можно успеть за заявленное клиентом время
+	elapsedMs := req.FinishedAt - req.StartedAt
+	maxTaps := int((elapsedMs*maxTapsPerSecond + 999) / 1000)
+	if maxTaps < 1 {
+		maxTaps = 1
+	}
+	count := req.Count
+	if count > maxTaps {
+		count = maxTaps
+	}
+
+	var result *TapBatchResponse
+	err := s.repo.WithTx(ctx, func(tx *sql.Tx) error {
+		rules := economy.Get()
+		userID, spent, err := s.repo.SpendEnergyForTapBatch(ctx, tx, telegramUser.ID, count, maxTapsPerSecond, rules.TapMinProfit)
+		if err != nil {
+			return err
+		}
+		if err := s.AddXP(ctx, tx, userID, int64(spent.Applied*rules.XPPerTap)); err != nil {
+			return err
+		}
+		if spent.Applied > 0 {
+			err := events.Publish(ctx, tx, events.Event{Type: events.TapsMade, UserID: userID, Amount: int64(spent.Applied)})
+			if err != nil {
+				return err
+			}
+		}
+		result = spent
+		result.Balance, err = s.ledger.Credit(ctx, tx, ledger.Entry{
+			UserID: userID,
+			Amount: result.Profit,
+			Reason: ledger.ReasonTap,
+		})
+		return err
+	})
+	if err != nil {
+		loger.Logger.Error("Ошибка при применении пачки тапов",
+			zap.Error(err),
+			zap.Int64("user_id", telegramUser.ID))
+		return c.JSON(500, err.Error())
+	}
+
+	if result.Applied == 0 && result.Energy <= 0 {
+		return s.outOfEnergy(c, telegramUser.ID)
+	}
+
+	if result.Applied < req.Count {
+		loger.Logger.Info("Пачка тапов урезана",
+			zap.Int64("user_id", telegramUser.ID),
+			zap.Int("requested", req.Count),
+			zap.Int("applied", result.Applied))
+	}
+
+	return c.JSON(200, result)
+}
+
+// nextEnergyIn возвращает время до восстановления следующей единицы энергии с учетом буста
+func nextEnergyIn(u *UserRepo, now time.Time) time.Duration {
+	interval := economy.Get().EnergyRegenInterval()
+	elapsed := regenElapsed(u, u.LastRestoration, now)
+	return regenRealTime(u, now, interval-elapsed%interval)
+}
+
+// outOfEnergy отвечает ошибкой с кодом out_of_energy и временем до следующей единицы энергии
+func (s *Service) outOfEnergy(c echo.Context, tg_id int64) error {
+	response := OutOfEnergyResponse{
+		Error: "Энергия закончилась",
+		Code:  codeOutOfEnergy,
+	}
+	user, err := s.repo.GetUser(c.Request().Context(), tg_id)
+	if err != nil {
+		loger.Logger.Error("Ошибка при получении пользователя",
+			zap.Error(err),
+			zap.Int64("user_id", tg_id))
+		return c.JSON(500, err.Error())
+	}
+	response.NextEnergyIn = int64((nextEnergyIn(user, time.Now()) + time.Second - 1) / time.Second)
+	return c.JSON(400, response)
+}
+
+func (s *Service) GetTransactionsHandler(c echo.Context) error {
+	ctx := c.Request().Context()
+	telegramUser := c.Get("telegram_user").(*middleware.TelegramUser)
+
+	var cursor int64
+	if v := c.QueryParam("cursor"); v != "" {
+		parsed, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || parsed < 0 {
+			return c.JSON(400, map[string]string{"error": "Неверный курсор"})
+		}
+		cursor = parsed
+	}
+	var limit int
+	if v := c.QueryParam("limit"); v != "" {
+		parsed, err := strconv.Atoi(v)
+		if err != nil || parsed <= 0 {
+			return c.JSON(400, map[string]string{"error": "Неверный лимит"})
+		}
+		limit = parsed
+	}
+
+	user, err := s.repo.GetUser(ctx, telegramUser.ID)
+	if err != nil {
+		loger.Logger.Error("Ошибка при получении пользователя",
+			zap.Error(err),
+			zap.Int64("user_id", telegramUser.ID))
+		return c.JSON(500, err.Error())
+	}
+
+	transactions, err := s.ledger.GetTransactions(ctx, user.ID, cursor, limit)
+	if err != nil {
+		loger.Logger.Error("Ошибка при получении истории операций",
+			zap.Error(err),
+			zap.Int("user_id", user.ID))
+		return c.JSON(500, err.Error())
+	}
+	return c.JSON(200, transactions)
+}
+
+func (s *Service) GetLevelHandler(c echo.Context) error {
+	ctx := c.Request().Context()
+	telegramUser := c.Get("telegram_user").(*middleware.TelegramUser)
+
+	user, err := s.repo.GetUser(ctx, telegramUser.ID)
+	if err != nil {
+		loger.Logger.Error("Ошибка при получении пользователя",
+			zap.Error(err),
+			zap.Int64("user_id", telegramUser.ID))
+		return c.JSON(500, err.Error())
+	}
+
+	response := LevelProgressResponse{
+		Level: user.Level,
+		XP:    user.XP,
+	}
+	current, err := s.repo.GetLevel(ctx, user.Level)
+	if err != nil && err != sql.ErrNoRows {
+		loger.Logger.Error("Ошибка при получении уровня",
+			zap.Error(err),
+			zap.Int("level", user.Level))
+		return c.JSON(500, err.Error())
+	}
+	if current != nil {
+		response.CurrentLevelXP = current.XPRequired
+	}
+	next, err := s.repo.GetLevel(ctx, user.Level+1)
+	if err != nil && err != sql.ErrNoRows {
+		loger.Logger.Error("Ошибка при получении уровня",
+			zap.Error(err),
+			zap.Int("level", user.Level+1))
+		return c.JSON(500, err.Error())
+	}
+	response.NextLevel = next
+	if next == nil {
+		response.Progress = 1
+	} else if span := next.XPRequired - response.CurrentLevelXP; span > 0 {
+		response.Progress = float64(user.XP-response.CurrentLevelXP) / float64(span)
+		if response.Progress < 0 {
+			response.Progress = 0
+		}
+		if response.Progress > 1 {
+			response.Progress = 1
+		}
+	}
+	return c.JSON(200, response)
+}
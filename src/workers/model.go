@@ -1,5 +1,17 @@
 package workers
 
+import (
+	"fmt"
+	"time"
+)
+
+// Типы условий открытия работника
+const (
+	PrerequisiteWorkerLevel = "worker_level"
+	PrerequisiteUserLevel   = "user_level"
+	PrerequisiteFriends     = "friends"
+)
+
 type WorkerRepo struct {
 	ID   int    `json:"id"`
 	Name string `json:"name"`
@@ -9,18 +21,84 @@ type WorkerRepo struct {
 
 }
 
+// CategoryRepo описывает категорию работников, например worker или army
+type CategoryRepo struct {
+	Slug string `json:"slug"`
+	Name string `json:"name"`
+}
+
 type WorkerUpgradeRepo struct {
 	ID   int    `json:"id"`
 	IdWorker int `json:"id_worker"`
 	Level int `json:"level"`
 	Cost int `json:"cost"`
 	Profit int `json:"profit"`
+	// DurationSeconds — сколько длится улучшение до этого уровня, 0 — применяется сразу
+	DurationSeconds int `json:"duration_seconds"`
+}
+
+// PendingUpgradeRepo описывает оплаченное улучшение, которое применится в CompletesAt
+type PendingUpgradeRepo struct {
+	ID          int
+	WorkerID    int
+	Upgrade     WorkerUpgradeRepo
+	CompletesAt time.Time
 }
 
 type UserWorkerRepo struct {
 	ID   int    `json:"id"`
 	IdWorker int `json:"id_worker"`
 	IdUpgrade int `json:"id_upgrade"`
+	Level int `json:"level"`
+}
+
+// WorkerLevelRepo описывает работника из каталога вместе с уровнем пользователя,
+// текущим и следующим улучшением
+type WorkerLevelRepo struct {
+	Worker WorkerRepo
+	// UserWorkerID — id строки user_workers, nil если работник не куплен
+	UserWorkerID *int
+	Level int
+	// Current — текущее улучшение, пустое для некупленного работника
+	Current WorkerUpgradeRepo
+	// Next — следующее улучшение, nil если достигнут максимальный уровень
+	Next *WorkerUpgradeRepo
+	// Pending — улучшение до следующего уровня, которое еще не завершилось
+	Pending *PendingUpgradeRepo
+}
+
+// PrerequisiteRepo описывает условие открытия работника и текущее продвижение пользователя к нему
+type PrerequisiteRepo struct {
+	WorkerID int
+	Type string
+	RequiredWorkerID *int
+	RequiredWorkerName *string
+	Value int
+	// Current — уровень нужного работника, уровень пользователя или число друзей
+	Current int
+}
+
+// Met сообщает, выполнено ли условие
+func (p PrerequisiteRepo) Met() bool {
+	return p.Current >= p.Value
+}
+
+// Description возвращает условие в виде, понятном пользователю
+func (p PrerequisiteRepo) Description() string {
+	switch p.Type {
+	case PrerequisiteWorkerLevel:
+		name := ""
+		if p.RequiredWorkerName != nil {
+			name = *p.RequiredWorkerName
+		}
+		return fmt.Sprintf("Улучшите «%s» до уровня %d", name, p.Value)
+	case PrerequisiteUserLevel:
+		return fmt.Sprintf("Достигните уровня %d", p.Value)
+	case PrerequisiteFriends:
+		return fmt.Sprintf("Пригласите друзей: %d", p.Value)
+	default:
+		return p.Type
+	}
 }
 
 type UserWorkerResponse struct {
@@ -32,5 +110,75 @@ type UserWorkerResponse struct {
 	Profit int `json:"profit"`
 	Cost int `json:"cost"`
 	AccessToUpgrade bool `json:"access_to_upgrade"`
-	
-}
\ No newline at end of file
+	// Locked — работник еще не открыт, UnlockCondition описывает невыполненные условия
+	Locked bool `json:"locked"`
+	UnlockCondition *string `json:"unlock_condition"`
+	// UpgradingToLevel — уровень, до которого идет улучшение, UpgradeCompletesIn — сколько секунд до его завершения,
+	// FinishGems — сколько кристаллов стоит завершить его сейчас
+	UpgradingToLevel *int `json:"upgrading_to_level"`
+	UpgradeCompletesIn *int64 `json:"upgrade_completes_in"`
+	FinishGems *int64 `json:"finish_gems"`
+}
+// UpgradeAllRequest задает необязательный бюджет массового улучшения
+type UpgradeAllRequest struct {
+	// Budget — сколько монет можно потратить, без него тратится весь баланс
+	Budget *int64 `json:"budget"`
+}
+
+// UpgradeSummary описывает одну покупку массового улучшения
+type UpgradeSummary struct {
+	WorkerID    int    `json:"worker_id"`
+	Name        string `json:"name"`
+	Level       int    `json:"level"`
+	Cost        int    `json:"cost"`
+	ProfitDelta int    `json:"profit_delta"`
+	// CompletesIn — через сколько секунд применится улучшение, 0 — применено сразу
+	CompletesIn int64  `json:"completes_in"`
+}
+
+type UpgradeAllResponse struct {
+	Upgrades      []UpgradeSummary `json:"upgrades"`
+	Spent         int64            `json:"spent"`
+	Balance       int64            `json:"balance"`
+	ProfitPerHour int              `json:"profit_per_hour"`
+}
+
+// ProfitDriftResponse описывает пользователя, у которого сохраненный доход в час неверен
+type ProfitDriftResponse struct {
+	UserID   int `json:"user_id"`
+	Stored   int `json:"stored"`
+	Expected int `json:"expected"`
+	// Fixed — доход в час исправлен этим запросом
+	Fixed bool `json:"fixed"`
+}
+
+// ReconcileFailureResponse описывает пользователя, доход которого не удалось исправить
+type ReconcileFailureResponse struct {
+	UserID int    `json:"user_id"`
+	Error  string `json:"error"`
+}
+
+type ReconcileProfitResponse struct {
+	DryRun bool                       `json:"dry_run"`
+	Users  []ProfitDriftResponse      `json:"users"`
+	Failed []ReconcileFailureResponse `json:"failed"`
+}
+
+// WorkerComboResponse описывает комбо работников и продвижение пользователя к нему
+type WorkerComboResponse struct {
+	ID           int                 `json:"id"`
+	Name         string              `json:"name"`
+	Description  string              `json:"description"`
+	BonusPercent int                 `json:"bonus_percent"`
+	Completed    bool                `json:"completed"`
+	Items        []ComboItemResponse `json:"items"`
+}
+
+type ComboItemResponse struct {
+	WorkerID int    `json:"worker_id"`
+	Name     string `json:"name"`
+	UrlImage string `json:"url_image"`
+	MinLevel int    `json:"min_level"`
+	Level    int    `json:"level"`
+	Done     bool   `json:"done"`
+}
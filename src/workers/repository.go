@@ -1,7 +1,10 @@
 package workers
 
 import (
+	"api/db"
+	"context"
 	"database/sql"
+	"time"
 )
 
 type WorkerRepository struct {
@@ -12,40 +15,191 @@ func NewWorkerRepository(db *sql.DB) *WorkerRepository {
 	return &WorkerRepository{db: db}
 }
 
-func (r *WorkerRepository) GetWorkers() ([]WorkerRepo, error) {
-	rows, err := r.db.Query("SELECT id, name, description, url_image, type FROM workers WHERE type = 'worker'")
+// workerLevelsQuery выбирает работников каталога вместе с уровнем пользователя $1,
+// текущим, следующим и незавершенным улучшением одним запросом
+const workerLevelsQuery = `SELECT w.id, w.name, w.description, w.url_image, w.type,
+		uw.id, COALESCE(uw.level, 0),
+		cur.id, cur.level, cur.cost, cur.profit,
+		nxt.id, nxt.level, nxt.cost, nxt.profit, nxt.duration_seconds,
+		pu.id, pu.completes_at
+	FROM workers w
+	LEFT JOIN user_workers uw ON uw.id_worker = w.id AND uw.id_user = $1
+	LEFT JOIN workers_upgrade cur ON cur.id_worker = w.id AND cur.level = uw.level
+	LEFT JOIN workers_upgrade nxt ON nxt.id_worker = w.id AND nxt.level = COALESCE(uw.level, 0) + 1
+	LEFT JOIN pending_upgrades pu ON pu.user_id = $1 AND pu.worker_id = w.id`
+
+func scanWorkerLevel(row interface{ Scan(dest ...any) error }) (WorkerLevelRepo, error) {
+	var level WorkerLevelRepo
+	var userWorkerID sql.NullInt64
+	var curID, curLevel, curCost, curProfit sql.NullInt64
+	var nextID, nextLevel, nextCost, nextProfit, nextDuration sql.NullInt64
+	var pendingID sql.NullInt64
+	var pendingCompletesAt sql.NullTime
+	err := row.Scan(
+		&level.Worker.ID, &level.Worker.Name, &level.Worker.Description, &level.Worker.UrlImage, &level.Worker.Type,
+		&userWorkerID, &level.Level,
+		&curID, &curLevel, &curCost, &curProfit,
+		&nextID, &nextLevel, &nextCost, &nextProfit, &nextDuration,
+		&pendingID, &pendingCompletesAt,
+	)
+	if err != nil {
+		return WorkerLevelRepo{}, err
+	}
+	if userWorkerID.Valid {
+		id := int(userWorkerID.Int64)
+		level.UserWorkerID = &id
+	}
+	level.Current = WorkerUpgradeRepo{IdWorker: level.Worker.ID, Level: level.Level}
+	if curID.Valid {
+		level.Current.ID = int(curID.Int64)
+		level.Current.Cost = int(curCost.Int64)
+		level.Current.Profit = int(curProfit.Int64)
+	}
+	if nextID.Valid {
+		level.Next = &WorkerUpgradeRepo{
+			ID:              int(nextID.Int64),
+			IdWorker:        level.Worker.ID,
+			Level:           int(nextLevel.Int64),
+			Cost:            int(nextCost.Int64),
+			Profit:          int(nextProfit.Int64),
+			DurationSeconds: int(nextDuration.Int64),
+		}
+	}
+	// Незавершенное улучшение всегда ведет на следующий уровень, поэтому купить его снова нельзя
+	if pendingID.Valid && level.Next != nil {
+		level.Pending = &PendingUpgradeRepo{
+			ID:          int(pendingID.Int64),
+			WorkerID:    level.Worker.ID,
+			Upgrade:     *level.Next,
+			CompletesAt: pendingCompletesAt.Time,
+		}
+		level.Next = nil
+	}
+	return level, nil
+}
+
+// GetWorkerLevels возвращает работников категории category с уровнями пользователя.
+// Пустая категория означает всех работников
+func (r *WorkerRepository) GetWorkerLevels(ctx context.Context, user_id int, category string) ([]WorkerLevelRepo, error) {
+	return getWorkerLevels(ctx, r.db, user_id, category)
+}
+
+func (r *WorkerRepository) GetWorkerLevelsTx(ctx context.Context, tx *sql.Tx, user_id int, category string) ([]WorkerLevelRepo, error) {
+	return getWorkerLevels(ctx, tx, user_id, category)
+}
+
+func getWorkerLevels(ctx context.Context, q queryer, user_id int, category string) ([]WorkerLevelRepo, error) {
+	rows, err := q.QueryContext(ctx, workerLevelsQuery+" WHERE $2 = '' OR w.type = $2 ORDER BY w.id", user_id, category)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	workers := []WorkerRepo{}
+	levels := []WorkerLevelRepo{}
 	for rows.Next() {
-		var worker WorkerRepo
-		err := rows.Scan(&worker.ID, &worker.Name, &worker.Description, &worker.UrlImage, &worker.Type)
+		level, err := scanWorkerLevel(rows)
 		if err != nil {
 			return nil, err
 		}
-		workers = append(workers, worker)
+		levels = append(levels, level)
 	}
-	return workers, nil
+	return levels, rows.Err()
 }
 
-func (r *WorkerRepository) GetArmy() ([]WorkerRepo, error) {
-	rows, err := r.db.Query("SELECT id, name, description, url_image, type FROM workers WHERE type = 'army'")
+// GetWorkerLevelTx возвращает работника с уровнем пользователя.
+// Если работника нет в каталоге, возвращает sql.ErrNoRows
+func (r *WorkerRepository) GetWorkerLevelTx(ctx context.Context, tx *sql.Tx, user_id int, worker_id int) (WorkerLevelRepo, error) {
+	return scanWorkerLevel(tx.QueryRowContext(ctx, workerLevelsQuery+" WHERE w.id = $2", user_id, worker_id))
+}
+
+// prerequisitesQuery выбирает условия открытия работников вместе с продвижением пользователя $1
+const prerequisitesQuery = `SELECT p.worker_id, p.type, p.required_worker_id, rw.name, p.value,
+		CASE p.type
+			WHEN 'worker_level' THEN COALESCE((SELECT level FROM user_workers WHERE id_user = $1 AND id_worker = p.required_worker_id), 0)
+			WHEN 'user_level' THEN (SELECT level FROM users WHERE id = $1)
+			WHEN 'friends' THEN (SELECT COUNT(*) FROM referrals WHERE referrer_id = $1)
+		END
+	FROM worker_prerequisites p
+	LEFT JOIN workers rw ON rw.id = p.required_worker_id`
+
+type queryer interface {
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+}
+
+func queryPrerequisites(ctx context.Context, q queryer, query string, args ...any) ([]PrerequisiteRepo, error) {
+	rows, err := q.QueryContext(ctx, query, args...)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	army := []WorkerRepo{}
+	prerequisites := []PrerequisiteRepo{}
 	for rows.Next() {
-		var worker WorkerRepo
-		err := rows.Scan(&worker.ID, &worker.Name, &worker.Description, &worker.UrlImage, &worker.Type)
-		if err != nil {
+		var p PrerequisiteRepo
+		var requiredWorkerID sql.NullInt64
+		var requiredWorkerName sql.NullString
+		if err := rows.Scan(&p.WorkerID, &p.Type, &requiredWorkerID, &requiredWorkerName, &p.Value, &p.Current); err != nil {
+			return nil, err
+		}
+		if requiredWorkerID.Valid {
+			id := int(requiredWorkerID.Int64)
+			p.RequiredWorkerID = &id
+		}
+		if requiredWorkerName.Valid {
+			p.RequiredWorkerName = &requiredWorkerName.String
+		}
+		prerequisites = append(prerequisites, p)
+	}
+	return prerequisites, rows.Err()
+}
+
+// GetPrerequisites возвращает условия открытия всех работников, сгруппированные по id работника
+func (r *WorkerRepository) GetPrerequisites(ctx context.Context, user_id int) (map[int][]PrerequisiteRepo, error) {
+	return getPrerequisitesByWorker(ctx, r.db, user_id)
+}
+
+func (r *WorkerRepository) GetPrerequisitesAllTx(ctx context.Context, tx *sql.Tx, user_id int) (map[int][]PrerequisiteRepo, error) {
+	return getPrerequisitesByWorker(ctx, tx, user_id)
+}
+
+func getPrerequisitesByWorker(ctx context.Context, q queryer, user_id int) (map[int][]PrerequisiteRepo, error) {
+	prerequisites, err := queryPrerequisites(ctx, q, prerequisitesQuery+" ORDER BY p.id", user_id)
+	if err != nil {
+		return nil, err
+	}
+	byWorker := map[int][]PrerequisiteRepo{}
+	for _, p := range prerequisites {
+		byWorker[p.WorkerID] = append(byWorker[p.WorkerID], p)
+	}
+	return byWorker, nil
+}
+
+func (r *WorkerRepository) GetPrerequisitesTx(ctx context.Context, tx *sql.Tx, user_id int, worker_id int) ([]PrerequisiteRepo, error) {
+	return queryPrerequisites(ctx, tx, prerequisitesQuery+" WHERE p.worker_id = $2 ORDER BY p.id", user_id, worker_id)
+}
+
+func (r *WorkerRepository) GetCategories(ctx context.Context) ([]CategoryRepo, error) {
+	rows, err := r.db.QueryContext(ctx, "SELECT slug, name FROM worker_categories ORDER BY sort_order, slug")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	categories := []CategoryRepo{}
+	for rows.Next() {
+		var category CategoryRepo
+		if err := rows.Scan(&category.Slug, &category.Name); err != nil {
 			return nil, err
 		}
-		army = append(army, worker)
+		categories = append(categories, category)
 	}
-	return army, nil
+	return categories, rows.Err()
+}
+
+func (r *WorkerRepository) CategoryExists(ctx context.Context, slug string) (bool, error) {
+	var exists bool
+	err := r.db.QueryRowContext(ctx, "SELECT EXISTS (SELECT 1 FROM worker_categories WHERE slug = $1)", slug).Scan(&exists)
+	if err != nil {
+		return false, err
+	}
+	return exists, nil
 }
 
 func (r *WorkerRepository) GetWorkerById(id int) (WorkerRepo, error) {
@@ -56,61 +210,230 @@ func (r *WorkerRepository) GetWorkerById(id int) (WorkerRepo, error) {
 	}
 	return worker, nil
 }
-func (r *WorkerRepository) GetUserWorker(worker_id int, user_id int) (UserWorkerRepo, error) {
-	var userWorker UserWorkerRepo
-	err := r.db.QueryRow("SELECT id, id_worker, id_upgrade FROM user_workers WHERE id_worker = $1 AND id_user = $2", worker_id, user_id).Scan(&userWorker.ID, &userWorker.IdWorker, &userWorker.IdUpgrade)
-	if err != nil {
-		return UserWorkerRepo{}, err
-	}
-	return userWorker, nil
+
+// WithTx выполняет fn в одной транзакции базы данных
+func (r *WorkerRepository) WithTx(ctx context.Context, fn func(tx *sql.Tx) error) error {
+	return db.WithTx(ctx, r.db, fn)
 }
 
-func (r *WorkerRepository) GetWorkerUpgrades(idWorker int, level int) (WorkerUpgradeRepo, error) {
-	var workerUpgrade WorkerUpgradeRepo
-	err := r.db.QueryRow("SELECT id, id_worker, level, cost, profit FROM workers_upgrade WHERE id_worker = $1 AND level = $2", idWorker, level).Scan(&workerUpgrade.ID, &workerUpgrade.IdWorker, &workerUpgrade.Level, &workerUpgrade.Cost, &workerUpgrade.Profit)
+// LockUserBalance блокирует строку пользователя до конца транзакции и возвращает актуальный баланс
+func (r *WorkerRepository) LockUserBalance(ctx context.Context, tx *sql.Tx, user_id int) (int64, error) {
+	var balance int64
+	err := tx.QueryRowContext(ctx, "SELECT balance FROM users WHERE id = $1 FOR UPDATE", user_id).Scan(&balance)
 	if err != nil {
-		return WorkerUpgradeRepo{}, err
+		return 0, err
 	}
-	return workerUpgrade, nil
+	return balance, nil
 }
 
-func (r *WorkerRepository) GetUpgradeById(id int) (WorkerUpgradeRepo, error) {
-	var workerUpgrade WorkerUpgradeRepo
-	err := r.db.QueryRow("SELECT id, id_worker, level, cost, profit FROM workers_upgrade WHERE id = $1", id).Scan(&workerUpgrade.ID, &workerUpgrade.IdWorker, &workerUpgrade.Level, &workerUpgrade.Cost, &workerUpgrade.Profit)
+func (r *WorkerRepository) CreateUserWorker(ctx context.Context, tx *sql.Tx, user_id int, worker_id int, upgrade WorkerUpgradeRepo) (int, error) {
+	var id int
+	err := tx.QueryRowContext(ctx, "INSERT INTO user_workers (id_user, id_worker, id_upgrade, level) VALUES ($1, $2, $3, $4) RETURNING id", user_id, worker_id, upgrade.ID, upgrade.Level).Scan(&id)
 	if err != nil {
-		return WorkerUpgradeRepo{}, err
+		return 0, err
 	}
-	return workerUpgrade, nil
+	return id, nil
 }
 
-func (r *WorkerRepository) UpdateUserBalance(tg_id int, balance int) error {
-	_, err := r.db.Exec("UPDATE users SET balance = $1 WHERE tg_id = $2", balance, tg_id)
+func (r *WorkerRepository) UpdateUserWorker(ctx context.Context, tx *sql.Tx, upgrade WorkerUpgradeRepo, user_worker_id int) error {
+	_, err := tx.ExecContext(ctx, "UPDATE user_workers SET id_upgrade = $1, level = $2 WHERE id = $3", upgrade.ID, upgrade.Level, user_worker_id)
 	if err != nil {
 		return err
 	}
 	return nil
 }
 
-func (r *WorkerRepository) CreateUserWorker(tg_id int, worker_id int, upgrade_id int) error {
-	_, err := r.db.Exec("INSERT INTO user_workers (id_user, id_worker, id_upgrade) VALUES ($1, $2, $3)", tg_id, worker_id, upgrade_id)
+// profitPerHourQuery вычисляет доход в час пользователя u по уровням купленных работников
+// с надбавкой собранных комбо. users.profit_per_hour всегда пересчитывается этим запросом,
+// а не изменяется на разницу
+const profitPerHourQuery = `SELECT (
+		SELECT COALESCE(SUM(wu.profit), 0)
+		FROM user_workers uw
+		JOIN workers_upgrade wu ON wu.id_worker = uw.id_worker AND wu.level = uw.level
+		WHERE uw.id_user = u.id
+	) * (100 + (` + comboBonusQuery + `)) / 100`
+
+// comboBonusQuery вычисляет суммарную надбавку в процентах от комбо, собранных пользователем u
+const comboBonusQuery = `SELECT COALESCE(SUM(c.bonus_percent), 0)
+	FROM worker_combos c
+	WHERE EXISTS (SELECT 1 FROM worker_combo_items ci WHERE ci.combo_id = c.id)
+		AND NOT EXISTS (
+			SELECT 1 FROM worker_combo_items ci
+			LEFT JOIN user_workers uw ON uw.id_worker = ci.worker_id AND uw.id_user = u.id
+			WHERE ci.combo_id = c.id AND COALESCE(uw.level, 0) < ci.min_level
+		)`
+
+// RecalculateProfitPerHour пересчитывает доход в час пользователя по купленным работникам и возвращает его
+func (r *WorkerRepository) RecalculateProfitPerHour(ctx context.Context, tx *sql.Tx, user_id int) (int, error) {
+	var profit int
+	err := tx.QueryRowContext(ctx,
+		"UPDATE users u SET profit_per_hour = ("+profitPerHourQuery+") WHERE u.id = $1 RETURNING u.profit_per_hour",
+		user_id,
+	).Scan(&profit)
 	if err != nil {
-		return err
+		return 0, err
 	}
-	return nil
+	return profit, nil
 }
 
-func (r *WorkerRepository) UpdateUserWorker(upgrade_id int, user_worker_id int) error {
-	_, err := r.db.Exec("UPDATE user_workers SET id_upgrade = $1 WHERE id = $2", upgrade_id, user_worker_id)
+// GetProfitDrifts возвращает пользователей, у которых сохраненный доход в час расходится с вычисленным
+func (r *WorkerRepository) GetProfitDrifts(ctx context.Context) ([]ProfitDriftResponse, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT id, profit_per_hour, expected FROM (
+			SELECT u.id, u.profit_per_hour, (`+profitPerHourQuery+`) AS expected FROM users u
+		) p
+		WHERE profit_per_hour <> expected
+		ORDER BY id`)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	return nil
+	defer rows.Close()
+	drifts := []ProfitDriftResponse{}
+	for rows.Next() {
+		var drift ProfitDriftResponse
+		if err := rows.Scan(&drift.UserID, &drift.Stored, &drift.Expected); err != nil {
+			return nil, err
+		}
+		drifts = append(drifts, drift)
+	}
+	return drifts, rows.Err()
 }
 
-func (r *WorkerRepository) UpdateUserWorkerProfit(id int, profit int, balance int) error {
-	_, err := r.db.Exec("UPDATE users SET profit_per_hour = profit_per_hour + $1, balance = balance - $2 WHERE id = $3", profit, balance, id)
+// GetUpgradesTx возвращает все уровни всех работников: id работника -> уровень -> улучшение
+func (r *WorkerRepository) GetUpgradesTx(ctx context.Context, tx *sql.Tx) (map[int]map[int]WorkerUpgradeRepo, error) {
+	rows, err := tx.QueryContext(ctx, "SELECT id, id_worker, level, cost, profit, duration_seconds FROM workers_upgrade")
 	if err != nil {
-		return err
+		return nil, err
 	}
-	return nil
+	defer rows.Close()
+	upgrades := map[int]map[int]WorkerUpgradeRepo{}
+	for rows.Next() {
+		var upgrade WorkerUpgradeRepo
+		if err := rows.Scan(&upgrade.ID, &upgrade.IdWorker, &upgrade.Level, &upgrade.Cost, &upgrade.Profit, &upgrade.DurationSeconds); err != nil {
+			return nil, err
+		}
+		if upgrades[upgrade.IdWorker] == nil {
+			upgrades[upgrade.IdWorker] = map[int]WorkerUpgradeRepo{}
+		}
+		upgrades[upgrade.IdWorker][upgrade.Level] = upgrade
+	}
+	return upgrades, rows.Err()
+}
+
+func (r *WorkerRepository) GetBalanceAndProfitTx(ctx context.Context, tx *sql.Tx, user_id int) (int64, int, error) {
+	var balance int64
+	var profit int
+	err := tx.QueryRowContext(ctx, "SELECT balance, profit_per_hour FROM users WHERE id = $1", user_id).Scan(&balance, &profit)
+	if err != nil {
+		return 0, 0, err
+	}
+	return balance, profit, nil
+}
+
+func (r *WorkerRepository) CreatePendingUpgrade(ctx context.Context, tx *sql.Tx, user_id int, upgrade WorkerUpgradeRepo, completes_at time.Time) (int, error) {
+	var id int
+	err := tx.QueryRowContext(ctx,
+		"INSERT INTO pending_upgrades (user_id, worker_id, upgrade_id, completes_at) VALUES ($1, $2, $3, $4) RETURNING id",
+		user_id, upgrade.IdWorker, upgrade.ID, completes_at,
+	).Scan(&id)
+	if err != nil {
+		return 0, err
+	}
+	return id, nil
+}
+
+const pendingUpgradesQuery = `SELECT pu.id, pu.worker_id, pu.completes_at,
+		wu.id, wu.id_worker, wu.level, wu.cost, wu.profit, wu.duration_seconds
+	FROM pending_upgrades pu
+	JOIN workers_upgrade wu ON wu.id = pu.upgrade_id`
+
+func scanPendingUpgrade(row interface{ Scan(dest ...any) error }) (PendingUpgradeRepo, error) {
+	var pending PendingUpgradeRepo
+	err := row.Scan(
+		&pending.ID, &pending.WorkerID, &pending.CompletesAt,
+		&pending.Upgrade.ID, &pending.Upgrade.IdWorker, &pending.Upgrade.Level, &pending.Upgrade.Cost, &pending.Upgrade.Profit, &pending.Upgrade.DurationSeconds,
+	)
+	if err != nil {
+		return PendingUpgradeRepo{}, err
+	}
+	return pending, nil
+}
+
+// GetDuePendingUpgradesTx блокирует и возвращает улучшения пользователя, завершившиеся к now, в порядке завершения
+func (r *WorkerRepository) GetDuePendingUpgradesTx(ctx context.Context, tx *sql.Tx, user_id int, now time.Time) ([]PendingUpgradeRepo, error) {
+	rows, err := tx.QueryContext(ctx,
+		pendingUpgradesQuery+" WHERE pu.user_id = $1 AND pu.completes_at <= $2 ORDER BY pu.completes_at, pu.id FOR UPDATE OF pu",
+		user_id, now,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	pending := []PendingUpgradeRepo{}
+	for rows.Next() {
+		p, err := scanPendingUpgrade(rows)
+		if err != nil {
+			return nil, err
+		}
+		pending = append(pending, p)
+	}
+	return pending, rows.Err()
+}
+
+// GetPendingUpgradeTx блокирует и возвращает незавершенное улучшение работника.
+// Если улучшения нет, возвращает sql.ErrNoRows
+func (r *WorkerRepository) GetPendingUpgradeTx(ctx context.Context, tx *sql.Tx, user_id int, worker_id int) (PendingUpgradeRepo, error) {
+	return scanPendingUpgrade(tx.QueryRowContext(ctx,
+		pendingUpgradesQuery+" WHERE pu.user_id = $1 AND pu.worker_id = $2 FOR UPDATE OF pu",
+		user_id, worker_id,
+	))
+}
+
+func (r *WorkerRepository) DeletePendingUpgrade(ctx context.Context, tx *sql.Tx, id int) error {
+	_, err := tx.ExecContext(ctx, "DELETE FROM pending_upgrades WHERE id = $1", id)
+	return err
+}
+
+// SpendGems списывает кристаллы и сообщает, хватило ли их
+func (r *WorkerRepository) SpendGems(ctx context.Context, tx *sql.Tx, user_id int, gems int64) (bool, error) {
+	result, err := tx.ExecContext(ctx, "UPDATE users SET gems = gems - $1 WHERE id = $2 AND gems >= $1", gems, user_id)
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return affected == 1, nil
+}
+
+// GetCombos возвращает все комбо с уровнями работников пользователя
+func (r *WorkerRepository) GetCombos(ctx context.Context, user_id int) ([]WorkerComboResponse, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT c.id, c.name, c.description, c.bonus_percent,
+			w.id, w.name, w.url_image, ci.min_level, COALESCE(uw.level, 0)
+		FROM worker_combos c
+		JOIN worker_combo_items ci ON ci.combo_id = c.id
+		JOIN workers w ON w.id = ci.worker_id
+		LEFT JOIN user_workers uw ON uw.id_worker = w.id AND uw.id_user = $1
+		ORDER BY c.id, w.id`, user_id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	combos := []WorkerComboResponse{}
+	for rows.Next() {
+		var combo WorkerComboResponse
+		var item ComboItemResponse
+		err := rows.Scan(&combo.ID, &combo.Name, &combo.Description, &combo.BonusPercent,
+			&item.WorkerID, &item.Name, &item.UrlImage, &item.MinLevel, &item.Level)
+		if err != nil {
+			return nil, err
+		}
+		if len(combos) == 0 || combos[len(combos)-1].ID != combo.ID {
+			combo.Items = []ComboItemResponse{}
+			combos = append(combos, combo)
+		}
+		last := &combos[len(combos)-1]
+		last.Items = append(last.Items, item)
+	}
+	return combos, rows.Err()
 }
@@ -0,0 +1,130 @@
+package workers
+
+import (
+	"database/sql"
+	"fmt"
+	"testing"
+	"time"
+)
+
+// fakeRow подставляет значения колонок в Scan так же, как строка результата запроса. nil — NULL
+type fakeRow []any
+
+func (r fakeRow) Scan(dest ...any) error {
+	if len(dest) != len(r) {
+		return fmt.Errorf("ожидалось %d колонок, получено %d", len(r), len(dest))
+	}
+	for i, d := range dest {
+		switch d := d.(type) {
+		case *int:
+			*d = r[i].(int)
+		case *string:
+			*d = r[i].(string)
+		case sql.Scanner:
+			if err := d.Scan(r[i]); err != nil {
+				return err
+			}
+		default:
+			return fmt.Errorf("неподдерживаемый тип %T", d)
+		}
+	}
+	return nil
+}
+
+// nullable возвращает значение колонки из LEFT JOIN: nil, если строки нет
+func nullable(ok bool, v int) any {
+	if !ok {
+		return nil
+	}
+	return int64(v)
+}
+
+// levelRow собирает строку workerLevelsQuery для работника workerID с уровнем пользователя userLevel
+// по каталогу улучшений catalog. userLevel 0 означает некупленного работника
+func levelRow(workerID int, userLevel int, catalog map[int]WorkerUpgradeRepo, pendingAt *time.Time) fakeRow {
+	cur, hasCur := catalog[userLevel]
+	next, hasNext := catalog[userLevel+1]
+	bought := userLevel > 0
+	var pendingID, completesAt any
+	if pendingAt != nil {
+		pendingID, completesAt = int64(1), *pendingAt
+	}
+	return fakeRow{
+		workerID, "Стажер", "Описание", "intern.png", "worker",
+		nullable(bought, 10), userLevel,
+		nullable(hasCur, cur.ID), nullable(hasCur, cur.Level), nullable(hasCur, cur.Cost), nullable(hasCur, cur.Profit),
+		nullable(hasNext, next.ID), nullable(hasNext, next.Level), nullable(hasNext, next.Cost), nullable(hasNext, next.Profit), nullable(hasNext, next.DurationSeconds),
+		pendingID, completesAt,
+	}
+}
+
+// testCatalog — работник с тремя уровнями
+var testCatalog = map[int]WorkerUpgradeRepo{
+	1: {ID: 101, IdWorker: 1, Level: 1, Cost: 100, Profit: 10},
+	2: {ID: 102, IdWorker: 1, Level: 2, Cost: 250, Profit: 30},
+	3: {ID: 103, IdWorker: 1, Level: 3, Cost: 600, Profit: 70, DurationSeconds: 3600},
+}
+
+func TestScanWorkerLevelWithoutNextLevel(t *testing.T) {
+	completesAt := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	tests := []struct {
+		name        string
+		row         fakeRow
+		wantLevel   int
+		wantProfit  int
+		wantBought  bool
+		wantPending bool
+	}{
+		{
+			name:       "максимальный уровень",
+			row:        levelRow(1, 3, testCatalog, nil),
+			wantLevel:  3,
+			wantProfit: 70,
+			wantBought: true,
+		},
+		{
+			name:      "пустой каталог улучшений",
+			row:       levelRow(1, 0, map[int]WorkerUpgradeRepo{}, nil),
+			wantLevel: 0,
+		},
+		{
+			name:        "следующий уровень уже улучшается",
+			row:         levelRow(1, 2, testCatalog, &completesAt),
+			wantLevel:   2,
+			wantProfit:  30,
+			wantBought:  true,
+			wantPending: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			level, err := scanWorkerLevel(tt.row)
+			if err != nil {
+				t.Fatalf("scanWorkerLevel: %v", err)
+			}
+			if level.Next != nil {
+				t.Errorf("Next = %+v, want nil", *level.Next)
+			}
+			if level.Level != tt.wantLevel || level.Current.Level != tt.wantLevel {
+				t.Errorf("Level = %d, Current.Level = %d, want %d", level.Level, level.Current.Level, tt.wantLevel)
+			}
+			if level.Current.Profit != tt.wantProfit {
+				t.Errorf("Current.Profit = %d, want %d", level.Current.Profit, tt.wantProfit)
+			}
+			if (level.UserWorkerID != nil) != tt.wantBought {
+				t.Errorf("UserWorkerID = %v, want bought %v", level.UserWorkerID, tt.wantBought)
+			}
+			if (level.Pending != nil) != tt.wantPending {
+				t.Fatalf("Pending = %+v, want pending %v", level.Pending, tt.wantPending)
+			}
+			if tt.wantPending {
+				if level.Pending.Upgrade != testCatalog[3] {
+					t.Errorf("Pending.Upgrade = %+v, want %+v", level.Pending.Upgrade, testCatalog[3])
+				}
+				if !level.Pending.CompletesAt.Equal(completesAt) {
+					t.Errorf("Pending.CompletesAt = %v, want %v", level.Pending.CompletesAt, completesAt)
+				}
+			}
+		})
+	}
+}
@@ -22,26 +22,78 @@ func RegisterRoutes(e *echo.Echo, service *WorkerService, config *config.Config)
 	workersGroup.Use(middleware.TelegramAuth(middleware.TelegramAuthConfig{
 		BotToken: handler.config.TELEGRAM_BOT_TOKEN,
 	}))
-	workersGroup.GET("/worker", handler.GetWorkers)
+	workersGroup.GET("", handler.GetWorkers)
+	workersGroup.GET("/categories", handler.GetCategories)
+	workersGroup.GET("/combos", handler.GetCombos)
+	// Старые маршруты категорий оставлены для совместимости с клиентами
+	workersGroup.GET("/worker", handler.GetWorkerCategory)
 	workersGroup.GET("/army", handler.GetArmy)
 	workersGroup.POST("/buy/:id", handler.BuyWorker)
+	workersGroup.POST("/upgrade-all", handler.UpgradeAll)
+	workersGroup.POST("/finish/:id", handler.FinishUpgrade)
+
+	adminGroup := e.Group("/admin/workers")
+	adminGroup.Use(middleware.AdminAuth(handler.config.ADMIN_TOKEN))
+	adminGroup.POST("/reconcile-profit", handler.ReconcileProfit)
 }
 
 // @Summary Получить список работников
-// @Description Получает список доступных работников
+// @Description Получает работников категории с уровнями пользователя. Без category возвращает всех работников
 // @Tags workers
 // @Accept json
 // @Produce json
+// @Param category query string false "Категория работников, например worker или army"
 // @Success 200 {array} UserWorkerResponse
+// @Failure 404 {object} map[string]string
 // @Failure 500 {object} map[string]string
-// @Router /workers/worker [get]
+// @Router /workers [get]
 // @Security TelegramAuth
 func (h *WorkerHandler) GetWorkers(c echo.Context) error {
 	return h.service.GetWorkers(c)
 }
 
+// @Summary Категории работников
+// @Description Получает список категорий работников
+// @Tags workers
+// @Accept json
+// @Produce json
+// @Success 200 {array} CategoryRepo
+// @Failure 500 {object} map[string]string
+// @Router /workers/categories [get]
+// @Security TelegramAuth
+func (h *WorkerHandler) GetCategories(c echo.Context) error {
+	return h.service.GetCategories(c)
+}
+
+// @Summary Комбо работников
+// @Description Получает комбо работников с продвижением пользователя. Собранное комбо увеличивает пассивный доход на bonus_percent процентов
+// @Tags workers
+// @Accept json
+// @Produce json
+// @Success 200 {array} WorkerComboResponse
+// @Failure 500 {object} map[string]string
+// @Router /workers/combos [get]
+// @Security TelegramAuth
+func (h *WorkerHandler) GetCombos(c echo.Context) error {
+	return h.service.GetCombos(c)
+}
+
+// @Summary Получить работников категории worker
+// @Description Устаревший маршрут, то же, что /workers?category=worker
+// @Tags workers
+// @Accept json
+// @Produce json
+// @Success 200 {array} UserWorkerResponse
+// @Failure 500 {object} map[string]string
+// @Router /workers/worker [get]
+// @Security TelegramAuth
+// @Deprecated
+func (h *WorkerHandler) GetWorkerCategory(c echo.Context) error {
+	return h.service.GetCategory(c, "worker")
+}
+
 // @Summary Получить армию
-// @Description Получает список доступных воинов
+// @Description Устаревший маршрут, то же, что /workers?category=army
 // @Tags workers
 // @Accept json
 // @Produce json
@@ -49,21 +101,71 @@ func (h *WorkerHandler) GetWorkers(c echo.Context) error {
 // @Failure 500 {object} map[string]string
 // @Router /workers/army [get]
 // @Security TelegramAuth
+// @Deprecated
 func (h *WorkerHandler) GetArmy(c echo.Context) error {
-	return h.service.GetArmy(c)
+	return h.service.GetCategory(c, "army")
 }
 
 // @Summary Купить работника
-// @Description Покупает или улучшает работника
+// @Description Покупает или улучшает работника и возвращает работников его категории. Купить неоткрытого работника нельзя.
+// @Description Улучшение с длительностью оплачивается сразу, а доход увеличивается после его завершения
 // @Tags workers
 // @Accept json
 // @Produce json
 // @Param id path int true "ID работника"
 // @Success 200 {array} UserWorkerResponse
 // @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string
 // @Failure 500 {object} map[string]string
 // @Router /workers/buy/{id} [post]
 // @Security TelegramAuth
 func (h *WorkerHandler) BuyWorker(c echo.Context) error {
 	return h.service.BuyWorker(c)
+}
+// @Summary Улучшить всех доступных работников
+// @Description Покупает самые выгодные по доходу на монету улучшения, пока хватает баланса или бюджета, в одной транзакции
+// @Tags workers
+// @Accept json
+// @Produce json
+// @Param request body UpgradeAllRequest false "Бюджет"
+// @Success 200 {object} UpgradeAllResponse
+// @Failure 400 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /workers/upgrade-all [post]
+// @Security TelegramAuth
+func (h *WorkerHandler) UpgradeAll(c echo.Context) error {
+	return h.service.UpgradeAll(c)
+}
+
+// @Summary Завершить улучшение досрочно
+// @Description Завершает идущее улучшение работника за кристаллы и возвращает работников его категории
+// @Tags workers
+// @Accept json
+// @Produce json
+// @Param id path int true "ID работника"
+// @Success 200 {array} UserWorkerResponse
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /workers/finish/{id} [post]
+// @Security TelegramAuth
+func (h *WorkerHandler) FinishUpgrade(c echo.Context) error {
+	return h.service.FinishUpgrade(c)
+}
+
+// @Summary Исправить доход в час
+// @Description Пересчитывает доход в час по купленным работникам у пользователей, где он расходится с сохраненным. Требует заголовок X-Admin-Token
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param dry_run query bool false "Только показать расхождения"
+// @Success 200 {object} ReconcileProfitResponse
+// @Failure 401 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /admin/workers/reconcile-profit [post]
+// @Security AdminAuth
+func (h *WorkerHandler) ReconcileProfit(c echo.Context) error {
+	return h.service.ReconcileProfit(c)
 }
\ No newline at end of file
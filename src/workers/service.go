@@ -1,145 +1,203 @@
 package workers
 
 import (
+	"context"
 	"database/sql"
+	"errors"
 	"github.com/labstack/echo/v4"
+	"api/src/ledger"
 	"api/src/middleware"
 	"api/src/user"
+	"api/src/core/economy"
+	"api/src/core/events"
 	"api/src/core/loger"
 	"strconv"
+	"strings"
+	"time"
 	"go.uber.org/zap"
 )
 
+var (
+	ErrMaxLevel          = errors.New("уровень работника максимальный")
+	ErrInsufficientFunds = errors.New("недостаточно средств")
+	ErrBalanceChanged    = errors.New("баланс изменился во время покупки")
+	ErrLocked            = errors.New("работник еще не открыт")
+	ErrUpgradeInProgress = errors.New("работник уже улучшается")
+	ErrNotEnoughGems     = errors.New("недостаточно кристаллов")
+)
+
+// maxBulkUpgrades ограничивает число покупок за один запрос массового улучшения
+const maxBulkUpgrades = 200
+
 type WorkerService struct {
 	repo *WorkerRepository
 	userService user.UserService
+	ledger *ledger.LedgerService
 }
 
-func NewWorkerService(repo *WorkerRepository, userService user.UserService) *WorkerService {
+func NewWorkerService(repo *WorkerRepository, userService user.UserService, ledger *ledger.LedgerService) *WorkerService {
 	return &WorkerService{
 		repo: repo,
 		userService: userService,
+		ledger: ledger,
 	}
 }
 
+// SubscribeEvents завершает наступившие улучшения работников перед каждым начислением пассивного дохода
+func (s *WorkerService) SubscribeEvents() {
+	events.Subscribe(events.AccrualStarted, func(ctx context.Context, tx *sql.Tx, event events.Event) error {
+		return s.completeUpgrades(ctx, tx, event.UserID)
+	})
+}
+
+// GetWorkers возвращает работников категории из параметра category, без параметра — всех работников
 func (s *WorkerService) GetWorkers(c echo.Context) error {
+	category := c.QueryParam("category")
+	if category != "" {
+		exists, err := s.repo.CategoryExists(c.Request().Context(), category)
+		if err != nil {
+			loger.Logger.Error("Ошибка при получении категории работников",
+				zap.Error(err),
+				zap.String("category", category))
+			return err
+		}
+		if !exists {
+			return c.JSON(404, map[string]string{"error": "Категория не найдена"})
+		}
+	}
+	return s.GetCategory(c, category)
+}
+
+// GetCategory отвечает списком работников категории category с уровнями пользователя.
+// Перед этим начисляется доход и завершаются наступившие улучшения
+func (s *WorkerService) GetCategory(c echo.Context, category string) error {
 	telegramUser := c.Get("telegram_user").(*middleware.TelegramUser)
-	user, err := s.userService.GetUser(c.Request().Context(), telegramUser.ID)
+	user, err := s.userService.AccrueUser(c.Request().Context(), telegramUser.ID)
 	if err != nil {
 		loger.Logger.Error("Ошибка при получении пользователя",
 			zap.Error(err),
 			zap.Int64("user_id", telegramUser.ID))
 		return err
 	}
-	workers, err := s.repo.GetWorkers()
+	result, err := s.GetCategoryWorkers(c.Request().Context(), category, user)
 	if err != nil {
 		loger.Logger.Error("Ошибка при получении работников",
-			zap.Error(err))
+			zap.Error(err),
+			zap.String("category", category))
 		return err
 	}
-	result, err := s.GetWorkersOrArmy(workers, user)
+	return c.JSON(200, result)
+}
+
+func (s *WorkerService) GetCategories(c echo.Context) error {
+	categories, err := s.repo.GetCategories(c.Request().Context())
 	if err != nil {
-		loger.Logger.Error("Ошибка при получении работников",
+		loger.Logger.Error("Ошибка при получении категорий работников",
 			zap.Error(err))
 		return err
 	}
-	return c.JSON(200, result)
+	return c.JSON(200, categories)
 }
 
-func (s *WorkerService) GetArmy(c echo.Context) error {
+// GetCombos возвращает комбо работников с уровнями пользователя
+func (s *WorkerService) GetCombos(c echo.Context) error {
+	ctx := c.Request().Context()
 	telegramUser := c.Get("telegram_user").(*middleware.TelegramUser)
-	user, err := s.userService.GetUser(c.Request().Context(), telegramUser.ID)
+	user, err := s.userService.AccrueUser(ctx, telegramUser.ID)
 	if err != nil {
 		loger.Logger.Error("Ошибка при получении пользователя",
 			zap.Error(err),
 			zap.Int64("user_id", telegramUser.ID))
 		return err
 	}
-	army, err := s.repo.GetArmy()
+	combos, err := s.repo.GetCombos(ctx, user.ID)
 	if err != nil {
-		loger.Logger.Error("Ошибка при получении работников",
-			zap.Error(err))
+		loger.Logger.Error("Ошибка при получении комбо работников",
+			zap.Error(err),
+			zap.Int("user_id", user.ID))
 		return err
 	}
-	result, err := s.GetWorkersOrArmy(army, user)
-	if err != nil {
-		loger.Logger.Error("Ошибка при получении работников",
-			zap.Error(err))
-		return err
+	for i := range combos {
+		combos[i].Completed = true
+		for j := range combos[i].Items {
+			item := &combos[i].Items[j]
+			item.Done = item.Level >= item.MinLevel
+			if !item.Done {
+				combos[i].Completed = false
+			}
+		}
 	}
-	return c.JSON(200, result)
+	return c.JSON(200, combos)
 }
 
-func (s *WorkerService) GetWorkersOrArmy(workers []WorkerRepo, user *user.UserRepo) ([]UserWorkerResponse, error) {
-	result := []UserWorkerResponse{}
-	
-	for _, worker := range workers {
-		userWorker, err := s.repo.GetUserWorker(worker.ID, int(user.ID))
-		var level int
-		if err != nil {
-			if err == sql.ErrNoRows {
-				loger.Logger.Info("Работник не найден",
-					zap.Int("worker_id", worker.ID),
-					zap.Int("user_id", int(user.ID)))
-				level = 0
-			} else {
-				loger.Logger.Error("Ошибка при получении работников пользователя",
-					zap.Error(err))
-				return nil, err
-			}
-		} else {
-			workerUpgrade, err := s.repo.GetUpgradeById(userWorker.IdUpgrade)
-			if err != nil {
-				loger.Logger.Error("Ошибка при получении уровня работника",
-					zap.Error(err))
-				return nil, err
-			}
-			level = workerUpgrade.Level
-		}
-		workerUpgradeUserLevel, err := s.repo.GetWorkerUpgrades(worker.ID, level)
-		if err != nil {
-			if err == sql.ErrNoRows {
-				loger.Logger.Info("Уровень работника не найден",
-					zap.Int("worker_id", worker.ID),
-					zap.Int("level", level))
-				workerUpgradeUserLevel = WorkerUpgradeRepo{Profit: 0}
-			} else {
-				loger.Logger.Error("Ошибка при получении работников пользователя",
-					zap.Error(err))
-				return nil, err
-			}
+// unlockCondition возвращает описание невыполненных условий открытия или nil, если все выполнены
+func unlockCondition(prerequisites []PrerequisiteRepo) *string {
+	unmet := []string{}
+	for _, p := range prerequisites {
+		if !p.Met() {
+			unmet = append(unmet, p.Description())
 		}
+	}
+	if len(unmet) == 0 {
+		return nil
+	}
+	condition := strings.Join(unmet, "; ")
+	return &condition
+}
 
-		workerUpgradeNextLevel, err := s.repo.GetWorkerUpgrades(worker.ID, level+1)
+// GetCategoryWorkers возвращает работников категории с уровнями пользователя одним запросом
+func (s *WorkerService) GetCategoryWorkers(ctx context.Context, category string, user *user.UserRepo) ([]UserWorkerResponse, error) {
+	levels, err := s.repo.GetWorkerLevels(ctx, user.ID, category)
+	if err != nil {
+		return nil, err
+	}
+	prerequisites, err := s.repo.GetPrerequisites(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	result := make([]UserWorkerResponse, 0, len(levels))
+	for _, level := range levels {
+		// Условия проверяются только при первой покупке, купленного работника можно улучшать дальше
+		var condition *string
+		if level.UserWorkerID == nil {
+			condition = unlockCondition(prerequisites[level.Worker.ID])
+		}
 		var accessToUpgrade bool
 		var cost int
-		if err != nil {
-			if err == sql.ErrNoRows {
-				accessToUpgrade = false
-				cost = 0
-			} else {
-				loger.Logger.Error("Ошибка при получении работников пользователя",
-					zap.Error(err))
-				return nil, err
-			}
-		} else {
-			if user.Balance >= int64(workerUpgradeNextLevel.Cost) {
-				accessToUpgrade = true
-			} else {
-				accessToUpgrade = false
+		if level.Next != nil {
+			accessToUpgrade = condition == nil && user.Balance >= int64(level.Next.Cost)
+			cost = level.Next.Cost
+		}
+		var upgradingToLevel *int
+		var completesIn, finishGems *int64
+		if level.Pending != nil {
+			remaining := level.Pending.CompletesAt.Sub(now)
+			seconds := int64((remaining + time.Second - 1) / time.Second)
+			if seconds < 0 {
+				seconds = 0
 			}
-			cost = workerUpgradeNextLevel.Cost
+			gems := economy.Get().UpgradeSkipGems(remaining)
+			upgradingToLevel = &level.Pending.Upgrade.Level
+			completesIn = &seconds
+			finishGems = &gems
 		}
 
 		result = append(result, UserWorkerResponse{
-			Id:              worker.ID,
-			Name:            worker.Name,
-			Description:     worker.Description,
-			UrlImage:        worker.UrlImage,
-			Level:           level,
-			Profit:          workerUpgradeUserLevel.Profit,
-			Cost:            cost,
-			AccessToUpgrade: accessToUpgrade,
+			Id:                 level.Worker.ID,
+			Name:               level.Worker.Name,
+			Description:        level.Worker.Description,
+			UrlImage:           level.Worker.UrlImage,
+			Level:              level.Level,
+			Profit:             level.Current.Profit,
+			Cost:               cost,
+			AccessToUpgrade:    accessToUpgrade,
+			Locked:             condition != nil,
+			UnlockCondition:    condition,
+			UpgradingToLevel:   upgradingToLevel,
+			UpgradeCompletesIn: completesIn,
+			FinishGems:         finishGems,
 		})
 	}
 	return result, nil
@@ -151,8 +209,9 @@ func (s *WorkerService) BuyWorker(c echo.Context) error {
 		return c.JSON(400, map[string]string{"error": "Неверный ID работника"})
 	}
 
+	ctx := c.Request().Context()
 	telegramUser := c.Get("telegram_user").(*middleware.TelegramUser)
-	user, err := s.userService.GetUser(c.Request().Context(), telegramUser.ID)
+	user, err := s.userService.GetUser(ctx, telegramUser.ID)
 	if err != nil {
 		loger.Logger.Error("Ошибка при получении пользователя",
 			zap.Error(err),
@@ -160,93 +219,404 @@ func (s *WorkerService) BuyWorker(c echo.Context) error {
 		return err
 	}
 
-	userWorker, err := s.repo.GetUserWorker(workerID, int(user.ID))
-	var level int
-	var upgradeLevel WorkerUpgradeRepo
-	nowProfit := 0
+	err = s.repo.WithTx(ctx, func(tx *sql.Tx) error {
+		return s.buyWorkerTx(ctx, tx, user, workerID)
+	})
+	switch err {
+	case nil:
+	case sql.ErrNoRows:
+		return c.JSON(404, map[string]string{"error": "Работник не найден"})
+	case ErrLocked:
+		return c.JSON(403, map[string]string{"error": "Работник еще не открыт"})
+	case ErrMaxLevel:
+		return c.JSON(400, map[string]string{"error": "Уровень работника максимальный"})
+	case ErrUpgradeInProgress:
+		return c.JSON(409, map[string]string{"error": "Работник уже улучшается"})
+	case ErrInsufficientFunds:
+		return c.JSON(400, map[string]string{"error": "Недостаточно средств"})
+	case ErrBalanceChanged:
+		return c.JSON(409, map[string]string{"error": "Баланс изменился, повторите покупку"})
+	default:
+		loger.Logger.Error("Ошибка при покупке работника",
+			zap.Error(err),
+			zap.Int("worker_id", workerID),
+			zap.Int("user_id", int(user.ID)))
+		return err
+	}
+
+	worker, err := s.repo.GetWorkerById(workerID)
 	if err != nil {
-		if err == sql.ErrNoRows {
-			level = 1
-			upgradeLevel, err = s.repo.GetWorkerUpgrades(workerID, 1)
-			if err != nil {
-				loger.Logger.Error("Ошибка при получении работника пользователя",
-					zap.Error(err),
-					zap.Int("worker_id", workerID),
-					zap.Int("user_id", int(user.ID)))
-				return err
-			}
-		} else {
-			loger.Logger.Error("Ошибка при получении работника пользователя",
-				zap.Error(err),
-				zap.Int("worker_id", workerID),
-				zap.Int("user_id", int(user.ID)))
+		loger.Logger.Error("Ошибка при получении работника",
+			zap.Error(err),
+			zap.Int("worker_id", workerID))
+		return err
+	}
+	return s.GetCategory(c, worker.Type)
+}
+
+// nextUpgrade возвращает улучшение, которое получит пользователь при покупке работника level,
+// или причину, по которой купить его нельзя
+func nextUpgrade(level WorkerLevelRepo) (WorkerUpgradeRepo, error) {
+	if level.Pending != nil {
+		return WorkerUpgradeRepo{}, ErrUpgradeInProgress
+	}
+	if level.Next == nil {
+		return WorkerUpgradeRepo{}, ErrMaxLevel
+	}
+	return *level.Next, nil
+}
+
+// buyWorkerTx списывает стоимость, создаёт или улучшает работника и увеличивает доход в час.
+// Строка пользователя блокируется, поэтому параллельные покупки выполняются по очереди
+func (s *WorkerService) buyWorkerTx(ctx context.Context, tx *sql.Tx, user *user.UserRepo, workerID int) error {
+	balance, err := s.lockAndCompleteUpgrades(ctx, tx, user.ID)
+	if err != nil {
+		return err
+	}
+
+	// У некупленного работника уровень 0, и следующим будет первый уровень
+	workerLevel, err := s.repo.GetWorkerLevelTx(ctx, tx, user.ID, workerID)
+	if err != nil {
+		return err
+	}
+	upgradeLevel, err := nextUpgrade(workerLevel)
+	if err != nil {
+		return err
+	}
+	if workerLevel.UserWorkerID == nil {
+		prerequisites, err := s.repo.GetPrerequisitesTx(ctx, tx, user.ID, workerID)
+		if err != nil {
 			return err
 		}
-	} else {
-		level = userWorker.IdUpgrade + 1
-		upgradeLevel, err = s.repo.GetWorkerUpgrades(workerID, level)
+		if unlockCondition(prerequisites) != nil {
+			return ErrLocked
+		}
+	}
+
+	if int64(upgradeLevel.Cost) > balance {
+		// Пользователь видел достаточный баланс, но он изменился параллельным запросом
+		if int64(upgradeLevel.Cost) <= user.Balance {
+			return ErrBalanceChanged
+		}
+		return ErrInsufficientFunds
+	}
+
+	err = s.applyUpgrade(ctx, tx, user.ID, &workerLevel)
+	if err == ledger.ErrInsufficientFunds {
+		return ErrBalanceChanged
+	}
+	return err
+}
+
+// applyUpgrade покупает следующий уровень работника level: списывает стоимость и начисляет опыт.
+// Улучшение без длительности сразу сохраняет уровень и увеличивает доход в час, и level указывает
+// на новый уровень. Иначе создается незавершенное улучшение, и оно записывается в level.Pending.
+// В обоих случаях level.Next становится nil
+func (s *WorkerService) applyUpgrade(ctx context.Context, tx *sql.Tx, userID int, level *WorkerLevelRepo) error {
+	upgradeLevel := *level.Next
+	_, err := s.ledger.Debit(ctx, tx, ledger.Entry{
+		UserID:     userID,
+		Amount:     int64(upgradeLevel.Cost),
+		Reason:     ledger.ReasonWorkerPurchase,
+		SourceType: ledger.SourceWorker,
+		SourceID:   int64(level.Worker.ID),
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := s.userService.AddXP(ctx, tx, userID, economy.Get().PurchaseXP(int64(upgradeLevel.Cost))); err != nil {
+		return err
+	}
+	err = events.Publish(ctx, tx, events.Event{
+		Type:   events.UpgradePurchased,
+		UserID: userID,
+		Amount: int64(upgradeLevel.Level),
+		Attrs:  map[string]string{"worker_id": strconv.Itoa(level.Worker.ID)},
+	})
+	if err != nil {
+		return err
+	}
+
+	level.Next = nil
+	if upgradeLevel.DurationSeconds > 0 {
+		completesAt := time.Now().Add(time.Duration(upgradeLevel.DurationSeconds) * time.Second)
+		id, err := s.repo.CreatePendingUpgrade(ctx, tx, userID, upgradeLevel, completesAt)
 		if err != nil {
-			if err == sql.ErrNoRows {
-				return c.JSON(400, map[string]string{"error": "Уровень работника максимальный"})
-			} else {
-				loger.Logger.Error("Ошибка при получении работника пользователя",
-					zap.Error(err),
-					zap.Int("worker_id", workerID),
-					zap.Int("user_id", int(user.ID)))
-				return err
-			}
+			return err
+		}
+		level.Pending = &PendingUpgradeRepo{
+			ID:          id,
+			WorkerID:    level.Worker.ID,
+			Upgrade:     upgradeLevel,
+			CompletesAt: completesAt,
 		}
-		nowLevel, err := s.repo.GetWorkerUpgrades(workerID, userWorker.IdUpgrade)
-		nowProfit = nowLevel.Profit
+		return nil
+	}
+	return s.setWorkerLevel(ctx, tx, userID, level, upgradeLevel)
+}
+
+// setWorkerLevel сохраняет оплаченный уровень upgradeLevel работника level и пересчитывает доход в час
+func (s *WorkerService) setWorkerLevel(ctx context.Context, tx *sql.Tx, userID int, level *WorkerLevelRepo, upgradeLevel WorkerUpgradeRepo) error {
+	if level.UserWorkerID == nil {
+		id, err := s.repo.CreateUserWorker(ctx, tx, userID, level.Worker.ID, upgradeLevel)
 		if err != nil {
-			loger.Logger.Error("Ошибка при получении работника пользователя",
-				zap.Error(err),
-				zap.Int("worker_id", workerID),
-				zap.Int("user_id", int(user.ID)))
 			return err
 		}
+		level.UserWorkerID = &id
+	} else if err := s.repo.UpdateUserWorker(ctx, tx, upgradeLevel, *level.UserWorkerID); err != nil {
+		return err
 	}
 
-	if int64(upgradeLevel.Cost) > user.Balance {
-		return c.JSON(400, map[string]string{"error": "Недостаточно средств"})
+	if _, err := s.repo.RecalculateProfitPerHour(ctx, tx, userID); err != nil {
+		return err
+	}
+	level.Level = upgradeLevel.Level
+	level.Current = upgradeLevel
+
+	return events.Publish(ctx, tx, events.Event{
+		Type:   events.WorkerBought,
+		UserID: userID,
+		Amount: int64(upgradeLevel.Level),
+		Attrs:  map[string]string{"worker_id": strconv.Itoa(level.Worker.ID), "worker_type": level.Worker.Type},
+	})
+}
+
+// lockAndCompleteUpgrades блокирует строку пользователя, применяет наступившие улучшения
+// и возвращает баланс с учетом начисленного по ним дохода
+func (s *WorkerService) lockAndCompleteUpgrades(ctx context.Context, tx *sql.Tx, userID int) (int64, error) {
+	if _, err := s.repo.LockUserBalance(ctx, tx, userID); err != nil {
+		return 0, err
+	}
+	if err := s.completeUpgrades(ctx, tx, userID); err != nil {
+		return 0, err
+	}
+	return s.repo.LockUserBalance(ctx, tx, userID)
+}
+
+// completeUpgrades применяет улучшения пользователя, время которых наступило. Доход до момента
+// завершения каждого улучшения начисляется по прежней ставке, поэтому прибавка действует ровно с него.
+// Строка пользователя должна быть уже заблокирована: как и в AccrueUser, блокировки берутся
+// в порядке users, затем pending_upgrades, иначе параллельные запросы могут взаимно заблокироваться
+func (s *WorkerService) completeUpgrades(ctx context.Context, tx *sql.Tx, userID int) error {
+	pending, err := s.repo.GetDuePendingUpgradesTx(ctx, tx, userID, time.Now())
+	if err != nil {
+		return err
+	}
+	for _, p := range pending {
+		if err := s.finishUpgrade(ctx, tx, userID, p, p.CompletesAt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// finishUpgrade применяет незавершенное улучшение pending в момент at
+func (s *WorkerService) finishUpgrade(ctx context.Context, tx *sql.Tx, userID int, pending PendingUpgradeRepo, at time.Time) error {
+	if err := s.userService.AccrueUntil(ctx, tx, userID, at); err != nil {
+		return err
+	}
+	level, err := s.repo.GetWorkerLevelTx(ctx, tx, userID, pending.WorkerID)
+	if err != nil {
+		return err
+	}
+	if err := s.setWorkerLevel(ctx, tx, userID, &level, pending.Upgrade); err != nil {
+		return err
+	}
+	return s.repo.DeletePendingUpgrade(ctx, tx, pending.ID)
+}
+
+// betterUpgrade сообщает, что улучшение a выгоднее b: больше прироста дохода на монету,
+// при равенстве — дешевле
+func betterUpgrade(a *WorkerLevelRepo, b *WorkerLevelRepo) bool {
+	gainA := int64(a.Next.Profit - a.Current.Profit)
+	gainB := int64(b.Next.Profit - b.Current.Profit)
+	left, right := gainA*int64(b.Next.Cost), gainB*int64(a.Next.Cost)
+	if left != right {
+		return left > right
+	}
+	if a.Next.Cost != b.Next.Cost {
+		return a.Next.Cost < b.Next.Cost
+	}
+	return a.Worker.ID < b.Worker.ID
+}
+
+// UpgradeAll жадно покупает самые выгодные по доходу на монету улучшения,
+// пока хватает баланса или бюджета из запроса
+func (s *WorkerService) UpgradeAll(c echo.Context) error {
+	ctx := c.Request().Context()
+	telegramUser := c.Get("telegram_user").(*middleware.TelegramUser)
+
+	var req UpgradeAllRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(400, map[string]string{"error": "Неверный формат запроса"})
+	}
+	if req.Budget != nil && *req.Budget < 0 {
+		return c.JSON(400, map[string]string{"error": "Бюджет не может быть отрицательным"})
 	}
 
-	err = s.repo.UpdateUserBalance(int(user.ID), int(user.Balance - int64(upgradeLevel.Cost)))
+	user, err := s.userService.GetUser(ctx, telegramUser.ID)
 	if err != nil {
-		loger.Logger.Error("Ошибка при обновлении баланса пользователя",
+		loger.Logger.Error("Ошибка при получении пользователя",
 			zap.Error(err),
-			zap.Int("user_id", int(user.ID)))
+			zap.Int64("user_id", telegramUser.ID))
 		return err
 	}
 
-	if level == 1 {
-		err = s.repo.CreateUserWorker(int(user.ID), workerID, upgradeLevel.ID)
+	var response UpgradeAllResponse
+	err = s.repo.WithTx(ctx, func(tx *sql.Tx) error {
+		balance, err := s.lockAndCompleteUpgrades(ctx, tx, user.ID)
 		if err != nil {
-			loger.Logger.Error("Ошибка при создании работника пользователя",
-				zap.Error(err),
-				zap.Int("user_id", int(user.ID)),
-				zap.Int("worker_id", workerID))
 			return err
 		}
-	} else {
-		err = s.repo.UpdateUserWorker(upgradeLevel.ID, userWorker.ID)
+		budget := balance
+		if req.Budget != nil && *req.Budget < budget {
+			budget = *req.Budget
+		}
+
+		levels, err := s.repo.GetWorkerLevelsTx(ctx, tx, user.ID, "")
 		if err != nil {
-			loger.Logger.Error("Ошибка при обновлении работника пользователя",
-				zap.Error(err),
-				zap.Int("user_id", int(user.ID)),
-				zap.Int("worker_id", workerID))
 			return err
 		}
+		upgrades, err := s.repo.GetUpgradesTx(ctx, tx)
+		if err != nil {
+			return err
+		}
+
+		prerequisites, err := s.repo.GetPrerequisitesAllTx(ctx, tx, user.ID)
+		if err != nil {
+			return err
+		}
+
+		response.Upgrades = []UpgradeSummary{}
+		for len(response.Upgrades) < maxBulkUpgrades {
+			var best *WorkerLevelRepo
+			hasLocked := false
+			for i := range levels {
+				level := &levels[i]
+				if level.Next == nil || int64(level.Next.Cost) > budget-response.Spent {
+					continue
+				}
+				if level.UserWorkerID == nil && unlockCondition(prerequisites[level.Worker.ID]) != nil {
+					hasLocked = true
+					continue
+				}
+				if best == nil || betterUpgrade(level, best) {
+					best = level
+				}
+			}
+			if best == nil {
+				break
+			}
+
+			cost, profitDelta, level := best.Next.Cost, best.Next.Profit-best.Current.Profit, best.Next.Level
+			if err := s.applyUpgrade(ctx, tx, user.ID, best); err != nil {
+				return err
+			}
+			// Работник с незавершенным улучшением до его завершения больше не улучшается
+			var completesIn int64
+			if best.Pending != nil {
+				completesIn = int64(best.Pending.Upgrade.DurationSeconds)
+			} else if next, ok := upgrades[best.Worker.ID][best.Level+1]; ok {
+				best.Next = &next
+			}
+			// Покупка и новый уровень пользователя могут открыть работников
+			if hasLocked {
+				prerequisites, err = s.repo.GetPrerequisitesAllTx(ctx, tx, user.ID)
+				if err != nil {
+					return err
+				}
+			}
+			response.Spent += int64(cost)
+			response.Upgrades = append(response.Upgrades, UpgradeSummary{
+				WorkerID:    best.Worker.ID,
+				Name:        best.Worker.Name,
+				Level:       level,
+				Cost:        cost,
+				ProfitDelta: profitDelta,
+				CompletesIn: completesIn,
+			})
+		}
+
+		response.Balance, response.ProfitPerHour, err = s.repo.GetBalanceAndProfitTx(ctx, tx, user.ID)
+		return err
+	})
+	if err != nil {
+		loger.Logger.Error("Ошибка при массовом улучшении работников",
+			zap.Error(err),
+			zap.Int("user_id", user.ID))
+		return err
 	}
-	
-	err = s.repo.UpdateUserWorkerProfit(int(user.ID), int(upgradeLevel.Profit - nowProfit), int(upgradeLevel.Cost))
+
+	loger.Logger.Info("Массовое улучшение работников",
+		zap.Int("user_id", user.ID),
+		zap.Int("upgrades", len(response.Upgrades)),
+		zap.Int64("spent", response.Spent))
+	return c.JSON(200, response)
+}
+
+// FinishUpgrade досрочно завершает улучшение работника за кристаллы
+// и возвращает работников его категории
+func (s *WorkerService) FinishUpgrade(c echo.Context) error {
+	workerID, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
-		loger.Logger.Error("Ошибка при обновлении прибыли пользователя",
+		return c.JSON(400, map[string]string{"error": "Неверный ID работника"})
+	}
+
+	ctx := c.Request().Context()
+	telegramUser := c.Get("telegram_user").(*middleware.TelegramUser)
+	user, err := s.userService.GetUser(ctx, telegramUser.ID)
+	if err != nil {
+		loger.Logger.Error("Ошибка при получении пользователя",
 			zap.Error(err),
-			zap.Int("user_id", int(user.ID)))
+			zap.Int64("user_id", telegramUser.ID))
+		return err
+	}
+
+	var gems int64
+	err = s.repo.WithTx(ctx, func(tx *sql.Tx) error {
+		// Пользователь блокируется раньше улучшения, в том же порядке, что и в AccrueUser
+		if _, err := s.repo.LockUserBalance(ctx, tx, user.ID); err != nil {
+			return err
+		}
+		pending, err := s.repo.GetPendingUpgradeTx(ctx, tx, user.ID, workerID)
+		if err != nil {
+			return err
+		}
+		now := time.Now()
+		at := pending.CompletesAt
+		if at.After(now) {
+			at = now
+			gems = economy.Get().UpgradeSkipGems(pending.CompletesAt.Sub(now))
+			ok, err := s.repo.SpendGems(ctx, tx, user.ID, gems)
+			if err != nil {
+				return err
+			}
+			if !ok {
+				return ErrNotEnoughGems
+			}
+		}
+		return s.finishUpgrade(ctx, tx, user.ID, pending, at)
+	})
+	switch err {
+	case nil:
+	case sql.ErrNoRows:
+		return c.JSON(404, map[string]string{"error": "Улучшение не найдено"})
+	case ErrNotEnoughGems:
+		return c.JSON(400, map[string]string{"error": "Недостаточно кристаллов"})
+	default:
+		loger.Logger.Error("Ошибка при завершении улучшения работника",
+			zap.Error(err),
+			zap.Int("worker_id", workerID),
+			zap.Int("user_id", user.ID))
 		return err
 	}
 
+	loger.Logger.Info("Улучшение работника завершено досрочно",
+		zap.Int("user_id", user.ID),
+		zap.Int("worker_id", workerID),
+		zap.Int64("gems", gems))
+
 	worker, err := s.repo.GetWorkerById(workerID)
 	if err != nil {
 		loger.Logger.Error("Ошибка при получении работника",
@@ -254,9 +624,54 @@ func (s *WorkerService) BuyWorker(c echo.Context) error {
 			zap.Int("worker_id", workerID))
 		return err
 	}
-	if worker.Type == "worker" {
-		return s.GetWorkers(c)
-	} else {
-		return s.GetArmy(c)
+	return s.GetCategory(c, worker.Type)
+}
+
+// ReconcileProfit находит пользователей с неверным доходом в час и исправляет его.
+// С dry_run=true только возвращает расхождения. Ошибка у одного пользователя
+// не останавливает исправление остальных и возвращается в списке failed
+func (s *WorkerService) ReconcileProfit(c echo.Context) error {
+	ctx := c.Request().Context()
+	dryRun := c.QueryParam("dry_run") == "true"
+
+	drifts, err := s.repo.GetProfitDrifts(ctx)
+	if err != nil {
+		loger.Logger.Error("Ошибка при поиске расхождений дохода в час",
+			zap.Error(err))
+		return c.JSON(500, map[string]string{"error": "Ошибка при поиске расхождений дохода в час"})
+	}
+	response := ReconcileProfitResponse{DryRun: dryRun, Users: drifts, Failed: []ReconcileFailureResponse{}}
+	if dryRun {
+		return c.JSON(200, response)
 	}
-}
\ No newline at end of file
+	for i := range response.Users {
+		drift := &response.Users[i]
+		err := s.repo.WithTx(ctx, func(tx *sql.Tx) error {
+			if _, err := s.lockAndCompleteUpgrades(ctx, tx, drift.UserID); err != nil {
+				return err
+			}
+			// Доход до исправления начисляется по ставке, которую пользователь видел
+			if err := s.userService.AccrueUntil(ctx, tx, drift.UserID, time.Now()); err != nil {
+				return err
+			}
+			_, err := s.repo.RecalculateProfitPerHour(ctx, tx, drift.UserID)
+			return err
+		})
+		if err != nil {
+			loger.Logger.Error("Ошибка при исправлении дохода в час",
+				zap.Error(err),
+				zap.Int("user_id", drift.UserID))
+			response.Failed = append(response.Failed, ReconcileFailureResponse{
+				UserID: drift.UserID,
+				Error:  err.Error(),
+			})
+			continue
+		}
+		drift.Fixed = true
+		loger.Logger.Warn("Доход в час исправлен",
+			zap.Int("user_id", drift.UserID),
+			zap.Int("stored", drift.Stored),
+			zap.Int("expected", drift.Expected))
+	}
+	return c.JSON(200, response)
+}
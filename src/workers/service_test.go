@@ -0,0 +1,179 @@
+package workers
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"api/src/user"
+	_ "github.com/lib/pq"
+)
+
+func TestNextUpgradeMultiLevel(t *testing.T) {
+	tests := []struct {
+		name       string
+		userLevel  int
+		wantLevel  int
+		wantCost   int
+		wantProfit int
+		// wantDelta — прирост дохода в час после покупки
+		wantDelta int
+		wantErr   error
+	}{
+		{name: "покупка первого уровня", userLevel: 0, wantLevel: 1, wantCost: 100, wantProfit: 10, wantDelta: 10},
+		{name: "улучшение 1→2", userLevel: 1, wantLevel: 2, wantCost: 250, wantProfit: 30, wantDelta: 20},
+		{name: "улучшение 2→3", userLevel: 2, wantLevel: 3, wantCost: 600, wantProfit: 70, wantDelta: 40},
+		{name: "максимальный уровень", userLevel: 3, wantErr: ErrMaxLevel},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			level, err := scanWorkerLevel(levelRow(1, tt.userLevel, testCatalog, nil))
+			if err != nil {
+				t.Fatalf("scanWorkerLevel: %v", err)
+			}
+			upgrade, err := nextUpgrade(level)
+			if err != tt.wantErr {
+				t.Fatalf("nextUpgrade error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr != nil {
+				return
+			}
+			if upgrade.Level != tt.wantLevel || upgrade.Cost != tt.wantCost || upgrade.Profit != tt.wantProfit {
+				t.Errorf("upgrade = %+v, want level %d, cost %d, profit %d", upgrade, tt.wantLevel, tt.wantCost, tt.wantProfit)
+			}
+			if upgrade.ID != testCatalog[tt.wantLevel].ID {
+				t.Errorf("upgrade.ID = %d, want %d", upgrade.ID, testCatalog[tt.wantLevel].ID)
+			}
+			if delta := upgrade.Profit - level.Current.Profit; delta != tt.wantDelta {
+				t.Errorf("profit delta = %d, want %d", delta, tt.wantDelta)
+			}
+		})
+	}
+}
+
+func TestNextUpgradeRejectsPending(t *testing.T) {
+	level, err := scanWorkerLevel(levelRow(1, 2, testCatalog, nil))
+	if err != nil {
+		t.Fatalf("scanWorkerLevel: %v", err)
+	}
+	level.Pending = &PendingUpgradeRepo{WorkerID: 1, Upgrade: *level.Next}
+	level.Next = nil
+	if _, err := nextUpgrade(level); err != ErrUpgradeInProgress {
+		t.Fatalf("nextUpgrade error = %v, want %v", err, ErrUpgradeInProgress)
+	}
+}
+
+// benchmarkWorkers — размер категории, на которой измеряется GetCategoryWorkers
+const benchmarkWorkers = 100
+
+// seedCategoryWorkers создает категорию из benchmarkWorkers работников с тремя уровнями улучшений,
+// пользователя с купленной половиной работников и условия открытия для каждого работника.
+// Возвращает slug категории, id пользователя и функцию удаления созданных данных
+func seedCategoryWorkers(ctx context.Context, db *sql.DB) (string, int, func(), error) {
+	suffix := time.Now().UnixNano()
+	slug := fmt.Sprintf("bench_%d", suffix%1_000_000_000)
+	var userID int
+	var workerIDs []int
+	cleanup := func() {
+		db.ExecContext(ctx, "DELETE FROM worker_prerequisites WHERE worker_id = ANY(SELECT id FROM workers WHERE type = $1)", slug)
+		db.ExecContext(ctx, "DELETE FROM user_workers WHERE id_user = $1", userID)
+		db.ExecContext(ctx, "DELETE FROM workers_upgrade WHERE id_worker = ANY(SELECT id FROM workers WHERE type = $1)", slug)
+		db.ExecContext(ctx, "DELETE FROM workers WHERE type = $1", slug)
+		db.ExecContext(ctx, "DELETE FROM worker_categories WHERE slug = $1", slug)
+		db.ExecContext(ctx, "DELETE FROM users WHERE id = $1", userID)
+	}
+
+	if _, err := db.ExecContext(ctx, "INSERT INTO worker_categories (slug, name) VALUES ($1, $1)", slug); err != nil {
+		return "", 0, cleanup, err
+	}
+	err := db.QueryRowContext(ctx,
+		"INSERT INTO users (tg_id, username, referral_code) VALUES ($1, $2, $2) RETURNING id",
+		-suffix, slug).Scan(&userID)
+	if err != nil {
+		return "", 0, cleanup, err
+	}
+	for i := 0; i < benchmarkWorkers; i++ {
+		var workerID int
+		err := db.QueryRowContext(ctx,
+			"INSERT INTO workers (name, description, url_image, type) VALUES ($1, '', '', $2) RETURNING id",
+			fmt.Sprintf("Работник %d", i), slug).Scan(&workerID)
+		if err != nil {
+			return "", 0, cleanup, err
+		}
+		workerIDs = append(workerIDs, workerID)
+		var firstUpgradeID int
+		for level := 1; level <= 3; level++ {
+			var upgradeID int
+			err := db.QueryRowContext(ctx,
+				"INSERT INTO workers_upgrade (id_worker, level, cost, profit) VALUES ($1, $2, $3, $4) RETURNING id",
+				workerID, level, level*100, level*10).Scan(&upgradeID)
+			if err != nil {
+				return "", 0, cleanup, err
+			}
+			if level == 1 {
+				firstUpgradeID = upgradeID
+			}
+		}
+		if i%2 == 0 {
+			_, err := db.ExecContext(ctx,
+				"INSERT INTO user_workers (id_user, id_worker, id_upgrade, level) VALUES ($1, $2, $3, 1)",
+				userID, workerID, firstUpgradeID)
+			if err != nil {
+				return "", 0, cleanup, err
+			}
+		}
+		// Каждый работник, кроме первого, требует улучшить предыдущего и уровень пользователя
+		if i > 0 {
+			_, err := db.ExecContext(ctx,
+				"INSERT INTO worker_prerequisites (worker_id, type, required_worker_id, value) VALUES ($1, 'worker_level', $2, 1)",
+				workerID, workerIDs[i-1])
+			if err != nil {
+				return "", 0, cleanup, err
+			}
+		}
+		_, err = db.ExecContext(ctx,
+			"INSERT INTO worker_prerequisites (worker_id, type, value) VALUES ($1, 'user_level', $2)",
+			workerID, i%5+1)
+		if err != nil {
+			return "", 0, cleanup, err
+		}
+	}
+	return slug, userID, cleanup, nil
+}
+
+// BenchmarkGetCategoryWorkers измеряет выдачу категории из 100 работников с условиями открытия.
+// Нужна база с примененными миграциями: TEST_DATABASE_URL=postgres://... go test -bench GetCategoryWorkers
+func BenchmarkGetCategoryWorkers(b *testing.B) {
+	dsn := os.Getenv("TEST_DATABASE_URL")
+	if dsn == "" {
+		b.Skip("TEST_DATABASE_URL не задан")
+	}
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		b.Fatalf("sql.Open: %v", err)
+	}
+	defer db.Close()
+	ctx := context.Background()
+
+	slug, userID, cleanup, err := seedCategoryWorkers(ctx, db)
+	defer cleanup()
+	if err != nil {
+		b.Fatalf("seedCategoryWorkers: %v", err)
+	}
+	service := NewWorkerService(NewWorkerRepository(db), nil, nil)
+	u := &user.UserRepo{ID: userID}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		workers, err := service.GetCategoryWorkers(ctx, slug, u)
+		if err != nil {
+			b.Fatalf("GetCategoryWorkers: %v", err)
+		}
+		if len(workers) != benchmarkWorkers {
+			b.Fatalf("len(workers) = %d, want %d", len(workers), benchmarkWorkers)
+		}
+	}
+}